@@ -6,16 +6,22 @@
 package gomap
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
 // Better AVL Tree
 type avlBetterTree struct {
-	c          comparator         // tree key compare
-	root       *avlBetterTreeNode // tree root
-	len        int64              // tree key pairs num
-	sync.Mutex                    // lock for concurrent safe
+	modCount     int64              // num of add or delete key, iterator use it to fail fast
+	c            comparator         // tree key compare
+	root         *avlBetterTreeNode // tree root
+	len          int64              // tree key pairs num
+	gen          uint64             // node gen less than it is shared by snapshot, copy before change
+	watch        *watchHub          // subscribers of change, nil until first watch
+	stats        mapStats           // live counters
+	sync.RWMutex                    // lock for concurrent safe, read lock for lookup
 }
 
 type avlBetterTreeNode struct {
@@ -25,6 +31,8 @@ type avlBetterTreeNode struct {
 	right         *avlBetterTreeNode
 	balanceFactor int64 // balance Factor
 	parent        *avlBetterTreeNode
+	size          int64  // key pairs num of the sub tree
+	gen           uint64 // gen of tree when node created or copied
 }
 
 // cal height
@@ -42,12 +50,57 @@ func (node *avlBetterTreeNode) height() int64 {
 	}
 }
 
+// Height follow the higher sub tree by balance factor, O(logN)
 func (tree *avlBetterTree) Height() int64 {
-	return tree.root.height()
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.height()
+}
+
+// height without lock, caller should lock first
+func (tree *avlBetterTree) height() int64 {
+	var h int64
+	for node := tree.root; node != nil; h++ {
+		if node.balanceFactor < 0 {
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	return h
+}
+
+// Stats live counters, change by atomic, no need write lock
+func (tree *avlBetterTree) Stats() Stats {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := tree.stats.load()
+	s.Len = tree.len
+	s.Height = tree.height()
+	s.HeightBound = avlHeightBound(s.Len)
+	return s
+}
+
+// cal sub tree size
+func (node *avlBetterTreeNode) treeSize() int64 {
+	if node == nil {
+		return 0
+	}
+
+	return node.size
 }
 
 func (tree *avlBetterTree) rotateLeft(h *avlBetterTreeNode) *avlBetterTreeNode {
 	if h != nil {
+		atomic.AddInt64(&tree.stats.leftRotations, 1)
+
+		// node shared by snapshot copy first
+		h = tree.own(h)
+		tree.own(h.right)
+
 		x := h.right
 		h.right = x.left
 
@@ -66,6 +119,10 @@ func (tree *avlBetterTree) rotateLeft(h *avlBetterTreeNode) *avlBetterTreeNode {
 		x.left = h
 		h.parent = x
 
+		// x take place of h, so size of h change
+		x.size = h.size
+		h.size = h.left.treeSize() + h.right.treeSize() + 1
+
 		// can see graph
 		h.balanceFactor += 1
 		if x.balanceFactor < 0 {
@@ -86,6 +143,11 @@ func (tree *avlBetterTree) rotateLeft(h *avlBetterTreeNode) *avlBetterTreeNode {
 // 对某节点右旋转
 func (tree *avlBetterTree) rotateRight(h *avlBetterTreeNode) *avlBetterTreeNode {
 	if h != nil {
+		atomic.AddInt64(&tree.stats.rightRotations, 1)
+
+		// node shared by snapshot copy first
+		h = tree.own(h)
+		tree.own(h.left)
 
 		// 看图理解
 		x := h.left
@@ -106,6 +168,10 @@ func (tree *avlBetterTree) rotateRight(h *avlBetterTreeNode) *avlBetterTreeNode
 		x.right = h
 		h.parent = x
 
+		// x take place of h, so size of h change
+		x.size = h.size
+		h.size = h.left.treeSize() + h.right.treeSize() + 1
+
 		// can see graph
 		h.balanceFactor -= 1
 		if x.balanceFactor > 0 {
@@ -127,24 +193,26 @@ func (tree *avlBetterTree) Put(key string, value interface{}) {
 	tree.Lock()
 	defer tree.Unlock()
 
+	tree.put(key, value)
+}
+
+// put key pairs without lock, caller should lock first
+func (tree *avlBetterTree) put(key string, value interface{}) {
 	if tree.root == nil {
-		// 根节点都是黑色
-		tree.root = &avlBetterTreeNode{
-			k: key,
-			v: value,
-		}
-		tree.len = 1
+		tree.insert(nil, 0, key, value)
 		return
 	}
 
 	var parent *avlBetterTreeNode
-	var cmp int64
+	var cmp, n int64
 	node := tree.root
 	for node != nil {
+		n++
 		cmp = tree.c(key, node.k)
 		parent = node
 		if cmp == 0 {
-			node.v = value
+			tree.stats.compare(key, n)
+			tree.update(node, value)
 			return
 		} else if cmp < 0 {
 			node = node.left
@@ -153,10 +221,34 @@ func (tree *avlBetterTree) Put(key string, value interface{}) {
 		}
 	}
 
+	tree.stats.compare(key, n)
+	tree.insert(parent, cmp, key, value)
+}
+
+// insert new node under parent, left when cmp less than 0, be root when parent is nil, without lock
+func (tree *avlBetterTree) insert(parent *avlBetterTreeNode, cmp int64, key string, value interface{}) {
+	if parent == nil {
+		tree.root = &avlBetterTreeNode{
+			k:    key,
+			v:    value,
+			size: 1,
+			gen:  tree.gen,
+		}
+		tree.len = 1
+		atomic.AddInt64(&tree.modCount, 1)
+		atomic.AddInt64(&tree.stats.puts, 1)
+		tree.watch.put(key, value, nil, false)
+		return
+	}
+
+	// path shared by snapshot copy first
+	parent = tree.own(parent)
 	newNode := &avlBetterTreeNode{
 		k:      key,
 		v:      value,
 		parent: parent,
+		size:   1,
+		gen:    tree.gen,
 	}
 
 	if cmp < 0 {
@@ -165,8 +257,15 @@ func (tree *avlBetterTree) Put(key string, value interface{}) {
 		parent.right = newNode
 	}
 
+	// all ancestors size add 1
+	for p := parent; p != nil; p = p.parent {
+		p.size++
+	}
+
+	var n int64
 	for parent != nil {
 		// balance factor change of parent
+		n++
 		cmp = tree.c(parent.k, key)
 		if cmp < 0 {
 			parent.balanceFactor -= 1
@@ -179,6 +278,7 @@ func (tree *avlBetterTree) Put(key string, value interface{}) {
 			break
 		} else if parent.balanceFactor < -1 {
 			// right higher
+			atomic.AddInt64(&tree.stats.rebalances, 1)
 			if parent.right.balanceFactor == 1 {
 				tree.rotateRight(parent.right)
 			}
@@ -187,6 +287,7 @@ func (tree *avlBetterTree) Put(key string, value interface{}) {
 			tree.rotateLeft(parent)
 			break
 		} else if parent.balanceFactor > 1 {
+			atomic.AddInt64(&tree.stats.rebalances, 1)
 			if parent.left.balanceFactor == -1 {
 				tree.rotateLeft(parent.left)
 			}
@@ -196,22 +297,51 @@ func (tree *avlBetterTree) Put(key string, value interface{}) {
 
 		parent = parent.parent
 	}
+	tree.stats.compare(key, n)
 
 	tree.len++
+	atomic.AddInt64(&tree.modCount, 1)
+	atomic.AddInt64(&tree.stats.puts, 1)
+	tree.watch.put(key, value, nil, false)
+}
+
+// update value of node without lock
+func (tree *avlBetterTree) update(node *avlBetterTreeNode, value interface{}) {
+	node = tree.own(node)
+	old := node.v
+	node.v = value
+	atomic.AddInt64(&tree.stats.puts, 1)
+	tree.watch.put(node.k, value, old, true)
 }
 
 func (tree *avlBetterTree) Delete(key string) {
 	tree.Lock()
 	defer tree.Unlock()
 
+	tree.deleteKey(key)
+}
+
+// delete key without lock, caller should lock first
+func (tree *avlBetterTree) deleteKey(key string) (value interface{}, exist bool) {
 	if tree.len == 0 {
 		return
 	}
 
-	var cmp int64
+	node := tree.find(key)
+	if node == nil {
+		return
+	}
+
+	return tree.deleteNode(node), true
+}
+
+// find key in tree, without lock
+func (tree *avlBetterTree) find(key string) *avlBetterTreeNode {
+	var n int64
 	node := tree.root
 	for node != nil {
-		cmp = tree.c(key, node.k)
+		n++
+		cmp := tree.c(key, node.k)
 		if cmp == 0 {
 			break
 		} else if cmp < 0 {
@@ -221,9 +351,15 @@ func (tree *avlBetterTree) Delete(key string) {
 		}
 	}
 
-	if node == nil {
-		return
-	}
+	tree.stats.compare(key, n)
+	return node
+}
+
+// delete node of tree, return value of node, without lock
+func (tree *avlBetterTree) deleteNode(node *avlBetterTreeNode) (value interface{}) {
+	// key and value may be replaced by other node, save first
+	key, value := node.k, node.v
+	node = tree.own(node)
 
 	var maxNode, minNode *avlBetterTreeNode
 	if node.left != nil {
@@ -235,6 +371,7 @@ func (tree *avlBetterTree) Delete(key string) {
 				maxNode = maxNode.right
 			}
 
+			node = tree.own(node)
 			node.k = maxNode.k
 			node.v = maxNode.v
 
@@ -247,6 +384,7 @@ func (tree *avlBetterTree) Delete(key string) {
 			}
 		}
 
+		node = tree.own(node)
 		node.k = maxNode.k
 		node.v = maxNode.v
 		node = maxNode // delete this node
@@ -260,6 +398,7 @@ func (tree *avlBetterTree) Delete(key string) {
 				minNode = minNode.left
 			}
 
+			node = tree.own(node)
 			node.k = minNode.k
 			node.v = minNode.v
 
@@ -269,11 +408,14 @@ func (tree *avlBetterTree) Delete(key string) {
 			}
 		}
 
+		node = tree.own(node)
 		node.k = minNode.k
 		node.v = minNode.v
 		node = minNode
 	}
 
+	// path to the deleted node shared by snapshot copy first
+	node = tree.own(node)
 	parent := node.parent
 	ps := node
 
@@ -285,11 +427,13 @@ func (tree *avlBetterTree) Delete(key string) {
 		}
 
 		if parent.balanceFactor < -1 {
+			atomic.AddInt64(&tree.stats.rebalances, 1)
 			if parent.right.balanceFactor == 1 {
 				tree.rotateRight(parent.right)
 			}
 			parent = tree.rotateLeft(parent)
 		} else if parent.balanceFactor > 1 {
+			atomic.AddInt64(&tree.stats.rebalances, 1)
 			if parent.left.balanceFactor == -1 {
 				tree.rotateLeft(parent.left)
 			}
@@ -306,6 +450,11 @@ func (tree *avlBetterTree) Delete(key string) {
 		parent = parent.parent
 	}
 
+	// all ancestors size sub 1
+	for p := node.parent; p != nil; p = p.parent {
+		p.size--
+	}
+
 	if node.parent != nil {
 		if node.parent.left == node {
 			node.parent.left = nil
@@ -321,12 +470,16 @@ func (tree *avlBetterTree) Delete(key string) {
 	}
 
 	tree.len--
+	atomic.AddInt64(&tree.modCount, 1)
+	atomic.AddInt64(&tree.stats.deletes, 1)
+	tree.watch.delete(key, value)
+	return value
 }
 
 // MinKey find min key pairs
 func (tree *avlBetterTree) MinKey() (key string, value interface{}, exist bool) {
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
 	if tree.root == nil {
 		// 如果是空树，返回空
 		return
@@ -346,8 +499,8 @@ func (node *avlBetterTreeNode) minNode() *avlBetterTreeNode {
 
 // MaxKey find max key pairs
 func (tree *avlBetterTree) MaxKey() (key string, value interface{}, exist bool) {
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
 	if tree.root == nil {
 		// 如果是空树，返回空
 		return
@@ -365,149 +518,214 @@ func (node *avlBetterTreeNode) maxNode() *avlBetterTreeNode {
 	return node.right.maxNode()
 }
 
-func (tree *avlBetterTree) Get(key string) (value interface{}, exist bool) {
-	tree.Lock()
-	defer tree.Unlock()
+// Floor find the greatest key pairs less than or equal to key
+func (tree *avlBetterTree) Floor(key string) (floorKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
 
-	if tree.root == nil {
+	node := tree.floor(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Ceiling find the least key pairs greater than or equal to key
+func (tree *avlBetterTree) Ceiling(key string) (ceilingKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Lower find the greatest key pairs strictly less than key
+func (tree *avlBetterTree) Lower(key string) (lowerKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.floor(key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Higher find the least key pairs strictly greater than key
+func (tree *avlBetterTree) Higher(key string) (higherKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, false)
+	if node == nil {
 		return
 	}
 
-	var cmp int64
+	return node.k, node.v, true
+}
+
+// 查找小于（inclusive 时小于等于）key 的最大节点
+func (tree *avlBetterTree) floor(key string, inclusive bool) *avlBetterTreeNode {
+	var candidate *avlBetterTreeNode
+	var n int64
 	node := tree.root
-	for {
-		cmp = tree.c(key, node.k)
-		if cmp == 0 {
-			return node.v, true
-		} else if cmp < 0 {
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp > 0 {
+			// 该节点比 key 小，是候选者，继续往右子树找更大的
+			candidate = node
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// 查找大于（inclusive 时大于等于）key 的最小节点
+func (tree *avlBetterTree) ceiling(key string, inclusive bool) *avlBetterTreeNode {
+	var candidate *avlBetterTreeNode
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp < 0 {
+			// 该节点比 key 大，是候选者，继续往左子树找更小的
+			candidate = node
 			node = node.left
 		} else {
 			node = node.right
 		}
+	}
 
-		if node == nil {
-			break
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// Rank num of keys strictly less than key
+func (tree *avlBetterTree) Rank(key string) int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	var rank, n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp > 0 {
+			// 左子树和该节点都比 key 小
+			rank += node.left.treeSize() + 1
+			node = node.right
+		} else {
+			node = node.left
 		}
 	}
 
-	return
+	tree.stats.compare(key, n)
+	return rank
 }
 
-func (tree *avlBetterTree) Contains(key string) (exist bool) {
-	tree.Lock()
-	defer tree.Unlock()
+// Select find the i-th smallest key pairs, i start from 0
+func (tree *avlBetterTree) Select(i int64) (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
 
-	if tree.root == nil {
+	if i < 0 || i >= tree.root.treeSize() {
 		return
 	}
 
-	var cmp int64
 	node := tree.root
-	for {
-		cmp = tree.c(key, node.k)
-		if cmp == 0 {
-			return true
-		} else if cmp < 0 {
+	for node != nil {
+		leftSize := node.left.treeSize()
+		if i < leftSize {
 			node = node.left
+		} else if i == leftSize {
+			return node.k, node.v, true
 		} else {
+			// 跳过左子树和该节点，往右子树找
+			i = i - leftSize - 1
 			node = node.right
 		}
-
-		if node == nil {
-			break
-		}
 	}
 
 	return
 }
 
-func (tree *avlBetterTree) Len() int64 {
-	return tree.len
-}
+func (tree *avlBetterTree) Get(key string) (value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
 
-func (tree *avlBetterTree) GetInt(key string) (value int, exist bool, err error) {
-	var v interface{}
-	v, exist = tree.Get(key)
-	if !exist {
-		return
-	}
+	return tree.get(key)
+}
 
-	value, ok := v.(int)
-	if !ok {
-		err = ReflectError(v)
-		return
+// get value of key without lock, caller should lock first
+func (tree *avlBetterTree) get(key string) (value interface{}, exist bool) {
+	if node := tree.find(key); node != nil {
+		return node.v, true
 	}
 
-	return value, true, nil
+	return
 }
 
-func (tree *avlBetterTree) GetInt64(key string) (value int64, exist bool, err error) {
-	var v interface{}
-	v, exist = tree.Get(key)
-	if !exist {
-		return
-	}
-
-	value, ok := v.(int64)
-	if !ok {
-		err = ReflectError(v)
-		return
-	}
+func (tree *avlBetterTree) Contains(key string) (exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
 
-	return value, true, nil
+	return tree.find(key) != nil
 }
 
-func (tree *avlBetterTree) GetString(key string) (value string, exist bool, err error) {
-	var v interface{}
-	v, exist = tree.Get(key)
-	if !exist {
-		return
-	}
+func (tree *avlBetterTree) Len() int64 {
+	tree.RLock()
+	defer tree.RUnlock()
 
-	value, ok := v.(string)
-	if !ok {
-		err = ReflectError(v)
-		return
-	}
+	return tree.len
+}
 
-	return value, true, nil
+func (tree *avlBetterTree) GetInt(key string) (value int, exist bool, err error) {
+	return getInt(tree.Get, key)
 }
 
-func (tree *avlBetterTree) GetFloat64(key string) (value float64, exist bool, err error) {
-	var v interface{}
-	v, exist = tree.Get(key)
-	if !exist {
-		return
-	}
+func (tree *avlBetterTree) GetInt64(key string) (value int64, exist bool, err error) {
+	return getInt64(tree.Get, key)
+}
 
-	value, ok := v.(float64)
-	if !ok {
-		err = ReflectError(v)
-		return
-	}
+func (tree *avlBetterTree) GetString(key string) (value string, exist bool, err error) {
+	return getString(tree.Get, key)
+}
 
-	return value, true, nil
+func (tree *avlBetterTree) GetFloat64(key string) (value float64, exist bool, err error) {
+	return getFloat64(tree.Get, key)
 }
 
 func (tree *avlBetterTree) GetBytes(key string) (value []byte, exist bool, err error) {
-	var v interface{}
-	v, exist = tree.Get(key)
-	if !exist {
-		return
-	}
-
-	value, ok := v.([]byte)
-	if !ok {
-		err = ReflectError(v)
-		return
-	}
-
-	return value, true, nil
+	return getBytes(tree.Get, key)
 }
 
 func (tree *avlBetterTree) KeySortedList() []string {
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
 	keyList := make([]string, 0, tree.len)
 	return tree.root.midOrder(keyList)
 }
@@ -524,6 +742,28 @@ func (node *avlBetterTreeNode) midOrder(keyList []string) []string {
 	return keyList
 }
 
+// KeySortedListDesc 逆中序遍历
+// reverse mid order get key list
+func (tree *avlBetterTree) KeySortedListDesc() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+	keyList := make([]string, 0, tree.len)
+	return tree.root.midOrderDesc(keyList)
+}
+
+func (node *avlBetterTreeNode) midOrderDesc(keyList []string) []string {
+	if node == nil {
+		return keyList
+	}
+
+	// 先右子树，再自己，最后左子树
+	keyList = node.right.midOrderDesc(keyList)
+	keyList = append(keyList, node.k)
+	keyList = node.left.midOrderDesc(keyList)
+
+	return keyList
+}
+
 func (tree *avlBetterTree) Check() bool {
 	if tree == nil || tree.root == nil {
 		return true
@@ -542,6 +782,11 @@ func (node *avlBetterTreeNode) isAVL(compare comparator) bool {
 		return true
 	}
 
+	if node.size != node.left.treeSize()+node.right.treeSize()+1 {
+		fmt.Printf("size %d != %d+%d+1\n", node.size, node.left.treeSize(), node.right.treeSize())
+		return false
+	}
+
 	if node.balanceFactor != node.left.height()-node.right.height() {
 		fmt.Printf("balanceFactor %d != %d-%d\n", node.balanceFactor, node.left.height(), node.right.height())
 		fmt.Printf("balanceFactor %#v != \n%#v-\n%#v\n", node, node.left, node.right)
@@ -662,15 +907,15 @@ func (node *avlBetterTreeNode) values() (key string, value interface{}) {
 }
 
 func (tree *avlBetterTree) KeyList() []string {
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
 
 	if tree.root == nil {
 		return []string{}
 	}
 
 	keyList := make([]string, 0, tree.len)
-	iterator := tree.Iterator()
+	iterator := tree.iterator()
 	for iterator.HasNext() {
 		k, _ := iterator.Next()
 		keyList = append(keyList, k)
@@ -681,13 +926,208 @@ func (tree *avlBetterTree) KeyList() []string {
 }
 
 func (tree *avlBetterTree) Iterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.iterator()
+}
+
+// layer order iterator, without lock
+func (tree *avlBetterTree) iterator() MapIterator {
 	q := new(linkQueue)
+	q.bind(&tree.modCount)
 	if tree.root != nil {
 		q.add(tree.root)
 	}
 	return q
 }
 
+// SafeIterator sorted iterator safe under concurrent write, snapshot mode is consistent, weak mode find next key every step
+func (tree *avlBetterTree) SafeIterator(mode IteratorMode) MapIterator {
+	return newSafeIterator(tree, mode)
+}
+
+// AscendIterator iterator sorted by key, from min to max
+func (tree *avlBetterTree) AscendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := new(linkStack)
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// DescendIterator iterator sorted by key, from max to min
+func (tree *avlBetterTree) DescendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := &linkStack{desc: true}
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// Range iterator key between from and to, sorted by key
+func (tree *avlBetterTree) Range(from, to string, opt RangeOption) MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.rangeIterator(from, to, opt)
+}
+
+// range iterator without lock
+func (tree *avlBetterTree) rangeIterator(from, to string, opt RangeOption) MapIterator {
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newRangeIterator(root, tree.c, from, to, opt)
+	it.bind(&tree.modCount)
+	return it
+}
+
+// Cursor bidirectional cursor, before min key at first
+func (tree *avlBetterTree) Cursor() Cursor {
+	return newCursor(tree)
+}
+
+// PrefixIterator iterator key with prefix, sorted by key
+func (tree *avlBetterTree) PrefixIterator(prefix string) (MapIterator, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return it, nil
+}
+
+// KeysWithPrefix key with prefix out to list sorted
+func (tree *avlBetterTree) KeysWithPrefix(prefix string) ([]string, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keyList := make([]string, 0)
+	for it.HasNext() {
+		k, _ := it.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList, nil
+}
+
+func (tree *avlBetterTree) prefixIterator(prefix string) (*rangeIterator, error) {
+	if !isDefaultComparator(tree.c) {
+		return nil, ErrPrefixComparator
+	}
+
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newPrefixIterator(root, tree.c, prefix)
+	it.bind(&tree.modCount)
+	return it, nil
+}
+
+// DeleteRange delete keys which from <= key <= to, return num of deleted keys
+func (tree *avlBetterTree) DeleteRange(from, to string) int64 {
+	return deleteRange(tree, from, to)
+}
+
+// PopMin find min key pairs and delete it
+func (tree *avlBetterTree) PopMin() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	key = tree.root.minNode().k
+	value, exist = tree.deleteKey(key)
+	return
+}
+
+// PopMax find max key pairs and delete it
+func (tree *avlBetterTree) PopMax() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	key = tree.root.maxNode().k
+	value, exist = tree.deleteKey(key)
+	return
+}
+
+// Ascend walk all key pairs from min to max, stop when fn return false
+func (tree *avlBetterTree) Ascend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, nil, fn)
+	}
+}
+
+// Descend walk all key pairs from max to min, stop when fn return false
+func (tree *avlBetterTree) Descend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		descend(tree.root, fn)
+	}
+}
+
+// AscendGreaterOrEqual walk key pairs which pivot <= key, stop when fn return false
+func (tree *avlBetterTree) AscendGreaterOrEqual(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &pivot, nil, fn)
+	}
+}
+
+// AscendLessThan walk key pairs which key < pivot, stop when fn return false
+func (tree *avlBetterTree) AscendLessThan(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, &pivot, fn)
+	}
+}
+
+// AscendRange walk key pairs which greaterOrEqual <= key < lessThan, stop when fn return false
+func (tree *avlBetterTree) AscendRange(greaterOrEqual, lessThan string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &greaterOrEqual, &lessThan, fn)
+	}
+}
+
 func (tree *avlBetterTree) SetComparator(c comparator) Map {
 	tree.Lock()
 	defer tree.Unlock()
@@ -697,3 +1137,146 @@ func (tree *avlBetterTree) SetComparator(c comparator) Map {
 
 	return tree
 }
+
+// Snapshot read only view of tree now, cost O(1)
+// all nodes now are shared with snapshot, later write copy the path rather than change them
+func (tree *avlBetterTree) Snapshot() ReadOnlyMap {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.gen++
+	return readOnlyMap{&avlBetterTree{
+		c:    tree.c,
+		root: tree.root,
+		len:  tree.len,
+	}}
+}
+
+// node shared by snapshot, copy it for tree to change, parent should copy first, so it is path copying
+// parent of shared node only used by tree, snapshot never read it, so change it directly
+func (tree *avlBetterTree) own(node *avlBetterTreeNode) *avlBetterTreeNode {
+	if node == nil || node.gen == tree.gen {
+		return node
+	}
+
+	n := new(avlBetterTreeNode)
+	*n = *node
+	n.gen = tree.gen
+
+	if node.parent == nil {
+		tree.root = n
+	} else {
+		p := tree.own(node.parent)
+		if p.left == node {
+			p.left = n
+		} else {
+			p.right = n
+		}
+
+		n.parent = p
+	}
+
+	if n.left != nil {
+		n.left.parent = n
+	}
+
+	if n.right != nil {
+		n.right.parent = n
+	}
+
+	return n
+}
+
+// Begin transaction, all writes apply on commit under one lock
+func (tree *avlBetterTree) Begin() Txn {
+	return beginTxn(tree)
+}
+
+// Compute find key once, put value return by fn, delete key when fn return keep false
+func (tree *avlBetterTree) Compute(key string, fn ComputeFunc) (value interface{}, exist bool) {
+	return tree.computeOp(key, fn.op())
+}
+
+// compute with lock, fn may leave the key unchanged
+func (tree *avlBetterTree) computeOp(key string, fn computeOpFunc) (value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	return tree.compute(key, fn)
+}
+
+// read modify write with one descent, without lock, caller should lock first
+// nothing write when fn leave the key unchanged, no event, no count and no copy of shared node
+func (tree *avlBetterTree) compute(key string, fn computeOpFunc) (value interface{}, exist bool) {
+	var parent *avlBetterTreeNode
+	var cmp, n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp = tree.c(key, node.k)
+		if cmp == 0 {
+			break
+		}
+
+		parent = node
+		if cmp < 0 {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+	tree.stats.compare(key, n)
+
+	// key not exist, parent is the place to insert
+	if node == nil {
+		value, op := fn(nil, false)
+		if op != computePut {
+			return nil, false
+		}
+
+		tree.insert(parent, cmp, key, value)
+		return value, true
+	}
+
+	value, op := fn(node.v, true)
+	switch op {
+	case computePut:
+		tree.update(node, value)
+		return value, true
+	case computeDelete:
+		tree.deleteNode(node)
+		return nil, false
+	}
+
+	return node.v, true
+}
+
+// PutIfAbsent put if key not exist, otherwise return the exist value
+func (tree *avlBetterTree) PutIfAbsent(key string, value interface{}) (actual interface{}, loaded bool) {
+	return putIfAbsent(tree.computeOp, key, value)
+}
+
+// Replace put only if key exist, return the old value
+func (tree *avlBetterTree) Replace(key string, value interface{}) (old interface{}, replaced bool) {
+	return replace(tree.computeOp, key, value)
+}
+
+// CompareAndSwap put new only if value of key == old
+func (tree *avlBetterTree) CompareAndSwap(key string, old, new interface{}) (swapped bool) {
+	return compareAndSwap(tree.computeOp, key, old, new)
+}
+
+// CompareAndDelete delete only if value of key == old
+func (tree *avlBetterTree) CompareAndDelete(key string, old interface{}) (deleted bool) {
+	return compareAndDelete(tree.computeOp, key, old)
+}
+
+// Watch watch put and delete of key, channel closed after ctx done
+func (tree *avlBetterTree) Watch(ctx context.Context, key string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, key, false, opt)
+}
+
+// WatchPrefix watch put and delete of keys with prefix
+func (tree *avlBetterTree) WatchPrefix(ctx context.Context, prefix string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, prefix, true, opt)
+}
\ No newline at end of file
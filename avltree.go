@@ -6,18 +6,23 @@
 package gomap
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
 // Deprecated
 // AVL Tree
 // Use recursion.
 type avlTree struct {
-	c          comparator   // tree key compare
-	root       *avlTreeNode // tree root node
-	len        int64        // tree key pairs num
-	sync.Mutex              // lock for concurrent safe
+	modCount     int64        // num of add or delete key, iterator use it to fail fast
+	c            comparator   // tree key compare
+	root         *avlTreeNode // tree root node
+	len          int64        // tree key pairs num
+	watch        *watchHub    // subscribers of change, nil until first watch
+	stats        mapStats     // live counters, recursion count in avlCounter and add once when done
+	sync.RWMutex              // lock for concurrent safe, read lock for lookup
 }
 
 // Deprecated
@@ -26,29 +31,77 @@ type avlTreeNode struct {
 	k      string       // key
 	v      interface{}  // value
 	height int64        // 该节点作为树根节点，树的高度，方便计算平衡因子
+	size   int64        // 该节点作为树根节点，树的节点数量，方便计算排名
 	left   *avlTreeNode // 左子树
 	right  *avlTreeNode // 右字树
 }
 
-func (node *avlTreeNode) h() int64 {
+// 子树的高度，节点里已经记录，不用遍历
+func (node *avlTreeNode) treeHeight() int64 {
 	if node == nil {
 		return 0
 	}
 
-	lh := node.left.h()
-	rh := node.right.h()
-	if lh > rh {
-		return lh + 1
-	} else {
-		return rh + 1
-	}
+	return node.height
 }
 
 func (tree *avlTree) Height() int64 {
-	return tree.root.h()
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.root.treeHeight()
+}
+
+// Stats 实时统计
+// Deprecated
+func (tree *avlTree) Stats() Stats {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := tree.stats.load()
+	s.Len = tree.len
+	s.Height = tree.root.treeHeight()
+	s.HeightBound = avlHeightBound(s.Len)
+	return s
 }
 
-// 更新节点的树高度
+// 一次操作的计数，递归的节点不知道树，带着它往下走，操作结束后一次性加到树的统计
+type avlCounter struct {
+	c              comparator
+	compares       int64
+	leftRotations  int64
+	rightRotations int64
+	rebalances     int64
+}
+
+// 比较键并计数
+func (cnt *avlCounter) compare(key1, key2 string) int64 {
+	cnt.compares++
+	return cnt.c(key1, key2)
+}
+
+// 把一次操作的计数加到树的统计，没有变化的不做原子操作
+func (tree *avlTree) count(key string, cnt *avlCounter) {
+	tree.stats.compare(key, cnt.compares)
+	if cnt.rebalances == 0 {
+		return
+	}
+
+	atomic.AddInt64(&tree.stats.leftRotations, cnt.leftRotations)
+	atomic.AddInt64(&tree.stats.rightRotations, cnt.rightRotations)
+	atomic.AddInt64(&tree.stats.rebalances, cnt.rebalances)
+}
+
+// 子树的节点数量
+func (node *avlTreeNode) treeSize() int64 {
+	if node == nil {
+		return 0
+	}
+
+	return node.size
+}
+
+// 更新节点的树高度，顺便更新子树节点数量
 func (node *avlTreeNode) updateHeight() {
 	if node == nil {
 		return
@@ -71,6 +124,9 @@ func (node *avlTreeNode) updateHeight() {
 	}
 	// 高度加上自己那一层
 	node.height = maxHeight + 1
+
+	// 节点数量加上自己
+	node.size = node.left.treeSize() + node.right.treeSize() + 1
 }
 
 // 计算平衡因子
@@ -156,28 +212,43 @@ func (tree *avlTree) Put(key string, value interface{}) {
 	tree.Lock()
 	defer tree.Unlock()
 
+	tree.put(key, value)
+}
+
+// 添加键值对，不加锁，调用者需要先加锁
+// Deprecated
+func (tree *avlTree) put(key string, value interface{}) {
 	add := false
+	var old interface{}
+	cnt := &avlCounter{c: tree.c}
 	if tree.root != nil {
-		node := tree.root.find(tree.c, key)
+		node := tree.root.find(cnt, key)
 		if node == nil {
 			add = true
+		} else {
+			old = node.v
 		}
 	} else {
 		add = true
 	}
 
 	// 往树根添加元素，会返回新的树根
-	tree.root = tree.root.put(tree.c, key, value)
+	tree.root = tree.root.put(cnt, key, value)
+	tree.count(key, cnt)
 
 	if add {
 		tree.len = tree.len + 1
+		atomic.AddInt64(&tree.modCount, 1)
 	}
+
+	atomic.AddInt64(&tree.stats.puts, 1)
+	tree.watch.put(key, value, old, !add)
 }
 
-func (node *avlTreeNode) put(compare comparator, key string, value interface{}) *avlTreeNode {
+func (node *avlTreeNode) put(cnt *avlCounter, key string, value interface{}) *avlTreeNode {
 	// 添加值到根节点node，如果node为空，那么让值成为新的根节点，树的高度为1
 	if node == nil {
-		return &avlTreeNode{k: key, v: value, height: 1}
+		return &avlTreeNode{k: key, v: value, height: 1, size: 1}
 	}
 
 	// 如果值重复，什么都不用做，直接更新次数
@@ -186,61 +257,25 @@ func (node *avlTreeNode) put(compare comparator, key string, value interface{})
 		return node
 	}
 
-	// 辅助变量
-	var newTreeNode *avlTreeNode
-
-	cmp := compare(key, node.k)
+	cmp := cnt.compare(key, node.k)
 	if cmp > 0 {
 		// 插入的值大于节点值，要从右子树继续插入
-		node.right = node.right.put(compare, key, value)
-		// 平衡因子，插入右子树后，要确保树根左子树的高度不能比右子树低一层。
-		factor := node.balanceFactor()
-		// 右子树的高度变高了，导致左子树-右子树的高度从-1变成了-2。
-		if factor == -2 {
-			cmp1 := compare(key, node.right.k)
-			if cmp1 > 0 {
-				// 表示在右子树上插上右儿子导致失衡，需要单左旋：
-				newTreeNode = node.leftRotation(node)
-			} else {
-				//表示在右子树上插上左儿子导致失衡，先右后左旋：
-				newTreeNode = node.rightLeftRotation(node)
-			}
-		}
+		node.right = node.right.put(cnt, key, value)
 	} else {
 		// 插入的值小于节点值，要从左子树继续插入
-		node.left = node.left.put(compare, key, value)
-		// 平衡因子，插入左子树后，要确保树根左子树的高度不能比右子树高一层。
-		factor := node.balanceFactor()
-		// 左子树的高度变高了，导致左子树-右子树的高度从1变成了2。
-		if factor == 2 {
-			cmp1 := compare(key, node.left.k)
-			if cmp1 < 0 {
-				// 表示在左子树上插上左儿子导致失衡，需要单右旋：
-				newTreeNode = node.rightRotation(node)
-			} else {
-				//表示在左子树上插上右儿子导致失衡，先左后右旋：
-				newTreeNode = node.leftRightRotation(node)
-			}
-		}
+		node.left = node.left.put(cnt, key, value)
 	}
 
-	if newTreeNode == nil {
-		// 表示什么旋转都没有，根节点没变，直接刷新树高度
-		node.updateHeight()
-		return node
-	} else {
-		// 旋转了，树根节点变了，需要刷新新的树根高度
-		newTreeNode.updateHeight()
-		return newTreeNode
-	}
+	// 插入后子树变高，左子树-右子树的高度可能变成了2或-2，需要旋转
+	return node.rebalance(cnt)
 }
 
 // MinKey find min key pairs
 // Deprecated
 func (tree *avlTree) MinKey() (key string, value interface{}, exist bool) {
 	// add lock
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
 	if tree.root == nil {
 		// 如果是空树，返回空
 		return
@@ -264,8 +299,8 @@ func (node *avlTreeNode) minNode() *avlTreeNode {
 // Deprecated
 func (tree *avlTree) MaxKey() (key string, value interface{}, exist bool) {
 	// add lock
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
 	if tree.root == nil {
 		// 如果是空树，返回空
 		return
@@ -284,26 +319,195 @@ func (node *avlTreeNode) maxNode() *avlTreeNode {
 	return node.right.maxNode()
 }
 
+// Floor find the greatest key pairs less than or equal to key
+// Deprecated
+func (tree *avlTree) Floor(key string) (floorKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.floor(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Ceiling find the least key pairs greater than or equal to key
+// Deprecated
+func (tree *avlTree) Ceiling(key string) (ceilingKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Lower find the greatest key pairs strictly less than key
+// Deprecated
+func (tree *avlTree) Lower(key string) (lowerKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.floor(key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Higher find the least key pairs strictly greater than key
+// Deprecated
+func (tree *avlTree) Higher(key string) (higherKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// 查找小于（inclusive 时小于等于）key 的最大节点
+func (tree *avlTree) floor(key string, inclusive bool) *avlTreeNode {
+	var candidate *avlTreeNode
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp > 0 {
+			// 该节点比 key 小，是候选者，继续往右子树找更大的
+			candidate = node
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// 查找大于（inclusive 时大于等于）key 的最小节点
+func (tree *avlTree) ceiling(key string, inclusive bool) *avlTreeNode {
+	var candidate *avlTreeNode
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp < 0 {
+			// 该节点比 key 大，是候选者，继续往左子树找更小的
+			candidate = node
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// Rank num of keys strictly less than key
+// Deprecated
+func (tree *avlTree) Rank(key string) int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	var rank, n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp > 0 {
+			// 左子树和该节点都比 key 小
+			rank += node.left.treeSize() + 1
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return rank
+}
+
+// Select find the i-th smallest key pairs, i start from 0
+// Deprecated
+func (tree *avlTree) Select(i int64) (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if i < 0 || i >= tree.root.treeSize() {
+		return
+	}
+
+	node := tree.root
+	for node != nil {
+		leftSize := node.left.treeSize()
+		if i < leftSize {
+			node = node.left
+		} else if i == leftSize {
+			return node.k, node.v, true
+		} else {
+			// 跳过左子树和该节点，往右子树找
+			i = i - leftSize - 1
+			node = node.right
+		}
+	}
+
+	return
+}
+
 // Get 查找指定节点
 // Deprecated
 func (tree *avlTree) Get(key string) (value interface{}, exist bool) {
 	// add lock
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
+
+	return tree.get(key)
+}
+
+// 查找键的值，不加锁，调用者需要先加锁
+// Deprecated
+func (tree *avlTree) get(key string) (value interface{}, exist bool) {
 	if tree.root == nil {
 		// 如果是空树，返回空
 		return
 	}
 
-	node := tree.root.find(tree.c, key)
+	cnt := &avlCounter{c: tree.c}
+	node := tree.root.find(cnt, key)
+	tree.stats.compare(key, cnt.compares)
 	if node == nil {
 		return nil, false
 	}
 	return node.v, true
 }
 
-func (node *avlTreeNode) find(compare comparator, key string) *avlTreeNode {
-	cmp := compare(key, node.k)
+func (node *avlTreeNode) find(cnt *avlCounter, key string) *avlTreeNode {
+	cmp := cnt.compare(key, node.k)
 	if cmp == 0 {
 		// 如果该节点刚刚等于该值，那么返回该节点
 		return node
@@ -313,14 +517,14 @@ func (node *avlTreeNode) find(compare comparator, key string) *avlTreeNode {
 			// 左子树为空，表示找不到该值了，返回nil
 			return nil
 		}
-		return node.left.find(compare, key)
+		return node.left.find(cnt, key)
 	} else {
 		// 如果查找的值大于节点值，从节点的右子树开始找
 		if node.right == nil {
 			// 右子树为空，表示找不到该值了，返回nil
 			return nil
 		}
-		return node.right.find(compare, key)
+		return node.right.find(cnt, key)
 	}
 }
 
@@ -330,36 +534,53 @@ func (tree *avlTree) Delete(key string) {
 	// add lock
 	tree.Lock()
 	defer tree.Unlock()
+
+	tree.deleteKey(key)
+}
+
+// 删除键，返回被删除的值，不加锁，调用者需要先加锁
+func (tree *avlTree) deleteKey(key string) (value interface{}, exist bool) {
 	if tree.len == 0 {
 		return
 	}
 
 	// 查找元素是否存在，不存在则退出
-	node := tree.root.find(tree.c, key)
+	cnt := &avlCounter{c: tree.c}
+	node := tree.root.find(cnt, key)
 	if node == nil {
+		tree.count(key, cnt)
 		return
 	}
 
-	tree.root = tree.root.delete(tree.c, key)
-	tree.len = tree.len - 1
+	// 删除时节点的值可能被替换，先保存
+	value = node.v
 
+	tree.root = tree.root.delete(cnt, key)
+	// 树根可能直接返回没有更新，这里刷新一下
+	tree.root.updateHeight()
+	tree.count(key, cnt)
+	tree.len = tree.len - 1
+	atomic.AddInt64(&tree.modCount, 1)
+	atomic.AddInt64(&tree.stats.deletes, 1)
+	tree.watch.delete(key, value)
+	return value, true
 }
 
-func (node *avlTreeNode) delete(compare comparator, key string) *avlTreeNode {
+func (node *avlTreeNode) delete(cnt *avlCounter, key string) *avlTreeNode {
 	if node == nil {
 		// 如果是空树，直接返回
 		return nil
 	}
 
-	cmp := compare(key, node.k)
+	cmp := cnt.compare(key, node.k)
 	if cmp < 0 {
 		// 从左子树开始删除
-		node.left = node.left.delete(compare, key)
+		node.left = node.left.delete(cnt, key)
 		// 删除后要更新该子树高度
 		node.left.updateHeight()
 	} else if cmp > 0 {
 		// 从右子树开始删除
-		node.right = node.right.delete(compare, key)
+		node.right = node.right.delete(cnt, key)
 		// 删除后要更新该子树高度
 		node.right.updateHeight()
 	} else {
@@ -385,7 +606,7 @@ func (node *avlTreeNode) delete(compare comparator, key string) *avlTreeNode {
 				node.v = maxNode.v
 
 				// 把最大的节点删掉
-				node.left = node.left.delete(compare, maxNode.k)
+				node.left = node.left.delete(cnt, maxNode.k)
 				// 删除后要更新该子树高度
 				node.left.updateHeight()
 			} else {
@@ -400,7 +621,7 @@ func (node *avlTreeNode) delete(compare comparator, key string) *avlTreeNode {
 				node.v = minNode.v
 
 				// 把最小的节点删掉
-				node.right = node.right.delete(compare, minNode.k)
+				node.right = node.right.delete(cnt, minNode.k)
 				// 删除后要更新该子树高度
 				node.right.updateHeight()
 			}
@@ -412,12 +633,14 @@ func (node *avlTreeNode) delete(compare comparator, key string) *avlTreeNode {
 				node.k = node.left.k
 				node.v = node.left.v
 				node.height = 1
+				node.size = 1
 				node.left = nil
 			} else if node.right != nil {
 				//第四种情况，删除的节点只有右子树，因为树的特征，可以知道右子树其实就只有一个节点，它本身，否则高度差就等于2了。
 				node.k = node.right.k
 				node.v = node.right.v
 				node.height = 1
+				node.size = 1
 				node.right = nil
 			}
 		}
@@ -427,45 +650,23 @@ func (node *avlTreeNode) delete(compare comparator, key string) *avlTreeNode {
 	}
 
 	// 左右子树递归删除节点后需要平衡
-	var newNode *avlTreeNode
-	// 相当删除了右子树的节点，左边比右边高了，不平衡
-	if cmp > 0 && node.balanceFactor() == 2 {
-		//fmt.Println("l-r=2 and l:", node.left.balanceFactor())
-		if node.left.balanceFactor() >= 0 { // why >0 will err must be checking
-			newNode = node.rightRotation(node)
-		} else {
-			newNode = node.leftRightRotation(node)
-		}
-		//  相当删除了左子树的节点，右边比左边高了，不平衡
-	} else if cmp < 0 && node.balanceFactor() == -2 {
-		//fmt.Println("l-r=-2 and l:", node.right.balanceFactor())
-		if node.right.balanceFactor() <= 0 {
-			newNode = node.leftRotation(node)
-		} else {
-			newNode = node.rightLeftRotation(node)
-		}
-	}
-
-	if newNode == nil {
-		node.updateHeight()
-		return node
-	} else {
-		newNode.updateHeight()
-		return newNode
-	}
+	return node.rebalance(cnt)
 }
 
 // Contains 查找指定节点
 // Deprecated
 func (tree *avlTree) Contains(key string) (exist bool) {
 	// add lock
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
 	if tree.root == nil {
 		// 如果是空树，返回空
 		return
 	}
-	node := tree.root.find(tree.c, key)
+	cnt := &avlCounter{c: tree.c}
+	node := tree.root.find(cnt, key)
+	tree.stats.compare(key, cnt.compares)
 	if node == nil {
 		return false
 	}
@@ -473,87 +674,30 @@ func (tree *avlTree) Contains(key string) (exist bool) {
 }
 
 func (tree *avlTree) Len() int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
 	return tree.len
 }
 
 func (tree *avlTree) GetInt(key string) (value int, exist bool, err error) {
-	var v interface{}
-	v, exist = tree.Get(key)
-	if !exist {
-		return
-	}
-
-	value, ok := v.(int)
-	if !ok {
-		err = ReflectError(v)
-		return
-	}
-
-	return value, true, nil
+	return getInt(tree.Get, key)
 }
 
 func (tree *avlTree) GetInt64(key string) (value int64, exist bool, err error) {
-	var v interface{}
-	v, exist = tree.Get(key)
-	if !exist {
-		return
-	}
-
-	value, ok := v.(int64)
-	if !ok {
-		err = ReflectError(v)
-		return
-	}
-
-	return value, true, nil
+	return getInt64(tree.Get, key)
 }
 
 func (tree *avlTree) GetString(key string) (value string, exist bool, err error) {
-	var v interface{}
-	v, exist = tree.Get(key)
-	if !exist {
-		return
-	}
-
-	value, ok := v.(string)
-	if !ok {
-		err = ReflectError(v)
-		return
-	}
-
-	return value, true, nil
+	return getString(tree.Get, key)
 }
 
 func (tree *avlTree) GetFloat64(key string) (value float64, exist bool, err error) {
-	var v interface{}
-	v, exist = tree.Get(key)
-	if !exist {
-		return
-	}
-
-	value, ok := v.(float64)
-	if !ok {
-		err = ReflectError(v)
-		return
-	}
-
-	return value, true, nil
+	return getFloat64(tree.Get, key)
 }
 
 func (tree *avlTree) GetBytes(key string) (value []byte, exist bool, err error) {
-	var v interface{}
-	v, exist = tree.Get(key)
-	if !exist {
-		return
-	}
-
-	value, ok := v.([]byte)
-	if !ok {
-		err = ReflectError(v)
-		return
-	}
-
-	return value, true, nil
+	return getBytes(tree.Get, key)
 }
 
 // KeySortedList 中序遍历
@@ -561,8 +705,8 @@ func (tree *avlTree) GetBytes(key string) (value []byte, exist bool, err error)
 // Deprecated
 func (tree *avlTree) KeySortedList() []string {
 	// add lock
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
 	keyList := make([]string, 0, tree.len)
 	return tree.root.midOrder(keyList)
 }
@@ -583,6 +727,29 @@ func (node *avlTreeNode) midOrder(keyList []string) []string {
 	return keyList
 }
 
+// KeySortedListDesc 逆中序遍历
+// reverse mid order get key list
+// Deprecated
+func (tree *avlTree) KeySortedListDesc() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+	keyList := make([]string, 0, tree.len)
+	return tree.root.midOrderDesc(keyList)
+}
+
+func (node *avlTreeNode) midOrderDesc(keyList []string) []string {
+	if node == nil {
+		return keyList
+	}
+
+	// 先右子树，再自己，最后左子树
+	keyList = node.right.midOrderDesc(keyList)
+	keyList = append(keyList, node.k)
+	keyList = node.left.midOrderDesc(keyList)
+
+	return keyList
+}
+
 // Check 验证是不是棵AVL树
 // Deprecated
 func (tree *avlTree) Check() bool {
@@ -604,6 +771,12 @@ func (node *avlTreeNode) isAVL(compare comparator) bool {
 		return true
 	}
 
+	// 子树节点数量要正确
+	if node.size != node.left.treeSize()+node.right.treeSize()+1 {
+		fmt.Printf("size %d != %d+%d+1\n", node.size, node.left.treeSize(), node.right.treeSize())
+		return false
+	}
+
 	// 左右子树都为空，那么是叶子节点
 	if node.left == nil && node.right == nil {
 		// 叶子节点高度应该为1
@@ -721,15 +894,15 @@ func (node *avlTreeNode) values() (key string, value interface{}) {
 }
 
 func (tree *avlTree) KeyList() []string {
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
 
 	if tree.root == nil {
 		return []string{}
 	}
 
 	keyList := make([]string, 0, tree.len)
-	iterator := tree.Iterator()
+	iterator := tree.iterator()
 	for iterator.HasNext() {
 		k, _ := iterator.Next()
 		keyList = append(keyList, k)
@@ -740,9 +913,387 @@ func (tree *avlTree) KeyList() []string {
 }
 
 func (tree *avlTree) Iterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.iterator()
+}
+
+// 层序迭代器，不加锁
+func (tree *avlTree) iterator() MapIterator {
 	q := new(linkQueue)
+	q.bind(&tree.modCount)
 	if tree.root != nil {
 		q.add(tree.root)
 	}
 	return q
+}
+
+// SafeIterator 并发写入时也安全的有序迭代器，快照模式要复制整棵树
+// Deprecated
+func (tree *avlTree) SafeIterator(mode IteratorMode) MapIterator {
+	return newSafeIterator(tree, mode)
+}
+
+// AscendIterator iterator sorted by key, from min to max
+// Deprecated
+func (tree *avlTree) AscendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := new(linkStack)
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// DescendIterator iterator sorted by key, from max to min
+// Deprecated
+func (tree *avlTree) DescendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := &linkStack{desc: true}
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// Range iterator key between from and to, sorted by key
+// Deprecated
+func (tree *avlTree) Range(from, to string, opt RangeOption) MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.rangeIterator(from, to, opt)
+}
+
+// 范围迭代器，不加锁
+// Deprecated
+func (tree *avlTree) rangeIterator(from, to string, opt RangeOption) MapIterator {
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newRangeIterator(root, tree.c, from, to, opt)
+	it.bind(&tree.modCount)
+	return it
+}
+
+// Cursor bidirectional cursor, before min key at first
+// Deprecated
+func (tree *avlTree) Cursor() Cursor {
+	return newCursor(tree)
+}
+
+// PrefixIterator iterator key with prefix, sorted by key
+// Deprecated
+func (tree *avlTree) PrefixIterator(prefix string) (MapIterator, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return it, nil
+}
+
+// KeysWithPrefix key with prefix out to list sorted
+// Deprecated
+func (tree *avlTree) KeysWithPrefix(prefix string) ([]string, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keyList := make([]string, 0)
+	for it.HasNext() {
+		k, _ := it.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList, nil
+}
+
+func (tree *avlTree) prefixIterator(prefix string) (*rangeIterator, error) {
+	if !isDefaultComparator(tree.c) {
+		return nil, ErrPrefixComparator
+	}
+
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newPrefixIterator(root, tree.c, prefix)
+	it.bind(&tree.modCount)
+	return it, nil
+}
+
+// DeleteRange delete keys which from <= key <= to, return num of deleted keys
+// Deprecated
+func (tree *avlTree) DeleteRange(from, to string) int64 {
+	return deleteRange(tree, from, to)
+}
+
+// PopMin find min key pairs and delete it
+// Deprecated
+func (tree *avlTree) PopMin() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	key = tree.root.minNode().k
+	value, exist = tree.deleteKey(key)
+	return
+}
+
+// PopMax find max key pairs and delete it
+// Deprecated
+func (tree *avlTree) PopMax() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	key = tree.root.maxNode().k
+	value, exist = tree.deleteKey(key)
+	return
+}
+
+// Ascend walk all key pairs from min to max, stop when fn return false
+// Deprecated
+func (tree *avlTree) Ascend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, nil, fn)
+	}
+}
+
+// Descend walk all key pairs from max to min, stop when fn return false
+// Deprecated
+func (tree *avlTree) Descend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		descend(tree.root, fn)
+	}
+}
+
+// AscendGreaterOrEqual walk key pairs which pivot <= key, stop when fn return false
+// Deprecated
+func (tree *avlTree) AscendGreaterOrEqual(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &pivot, nil, fn)
+	}
+}
+
+// AscendLessThan walk key pairs which key < pivot, stop when fn return false
+// Deprecated
+func (tree *avlTree) AscendLessThan(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, &pivot, fn)
+	}
+}
+
+// AscendRange walk key pairs which greaterOrEqual <= key < lessThan, stop when fn return false
+// Deprecated
+func (tree *avlTree) AscendRange(greaterOrEqual, lessThan string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &greaterOrEqual, &lessThan, fn)
+	}
+}
+
+// Snapshot read only view of tree now
+// Deprecated
+// recursion tree has no path copying, copy all nodes under read lock, writers wait, cost O(N)
+func (tree *avlTree) Snapshot() ReadOnlyMap {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return readOnlyMap{&avlTree{
+		c:    tree.c,
+		root: tree.root.clone(),
+		len:  tree.len,
+	}}
+}
+
+// 复制整棵子树
+func (node *avlTreeNode) clone() *avlTreeNode {
+	if node == nil {
+		return nil
+	}
+
+	n := new(avlTreeNode)
+	*n = *node
+	n.left = node.left.clone()
+	n.right = node.right.clone()
+	return n
+}
+
+// Begin 开始事务，提交时在一次加锁内写入所有修改
+// Deprecated
+func (tree *avlTree) Begin() Txn {
+	return beginTxn(tree)
+}
+
+// Compute 只查找一次，用 fn 的返回值更新键值对，keep 为 false 时删除
+// Deprecated
+func (tree *avlTree) Compute(key string, fn ComputeFunc) (value interface{}, exist bool) {
+	return tree.computeOp(key, fn.op())
+}
+
+// 加锁后一次查找完成读改写，fn 可以不修改键
+// Deprecated
+func (tree *avlTree) computeOp(key string, fn computeOpFunc) (value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	cnt := &avlCounter{c: tree.c}
+	tree.root, value, exist = tree.compute(cnt, tree.root, key, fn)
+	tree.count(key, cnt)
+	return
+}
+
+// 递归查找一次完成读改写，返回新的子树根节点，fn 不修改键时没有事件也不计数
+// Deprecated
+func (tree *avlTree) compute(cnt *avlCounter, node *avlTreeNode, key string, fn computeOpFunc) (root *avlTreeNode, value interface{}, exist bool) {
+	// 键不存在，在这里插入新节点
+	if node == nil {
+		value, op := fn(nil, false)
+		if op != computePut {
+			return nil, nil, false
+		}
+
+		tree.len = tree.len + 1
+		atomic.AddInt64(&tree.modCount, 1)
+		atomic.AddInt64(&tree.stats.puts, 1)
+		tree.watch.put(key, value, nil, false)
+		return &avlTreeNode{k: key, v: value, height: 1, size: 1}, value, true
+	}
+
+	cmp := cnt.compare(key, node.k)
+	if cmp == 0 {
+		old := node.v
+		value, op := fn(old, true)
+		switch op {
+		case computePut:
+			node.v = value
+			atomic.AddInt64(&tree.stats.puts, 1)
+			tree.watch.put(key, value, old, true)
+			return node, value, true
+		case computeKeep:
+			return node, old, true
+		}
+
+		// 删除该节点，从该节点开始删除，马上就能找到
+		tree.len = tree.len - 1
+		atomic.AddInt64(&tree.modCount, 1)
+		atomic.AddInt64(&tree.stats.deletes, 1)
+		root = node.delete(cnt, key)
+		root.updateHeight()
+		tree.watch.delete(key, old)
+		return root, nil, false
+	}
+
+	if cmp < 0 {
+		node.left, value, exist = tree.compute(cnt, node.left, key, fn)
+	} else {
+		node.right, value, exist = tree.compute(cnt, node.right, key, fn)
+	}
+
+	// 子树添加或删除了节点，可能失衡
+	return node.rebalance(cnt), value, exist
+}
+
+// 子树高度变化后重新平衡，返回新的子树根节点，旋转次数记到 cnt
+func (node *avlTreeNode) rebalance(cnt *avlCounter) *avlTreeNode {
+	factor := node.balanceFactor()
+	if factor == 2 {
+		// 左边高了
+		cnt.rebalances++
+		cnt.rightRotations++
+		if node.left.balanceFactor() >= 0 {
+			return node.rightRotation(node)
+		}
+
+		cnt.leftRotations++
+		return node.leftRightRotation(node)
+	} else if factor == -2 {
+		// 右边高了
+		cnt.rebalances++
+		cnt.leftRotations++
+		if node.right.balanceFactor() <= 0 {
+			return node.leftRotation(node)
+		}
+
+		cnt.rightRotations++
+		return node.rightLeftRotation(node)
+	}
+
+	node.updateHeight()
+	return node
+}
+
+// PutIfAbsent put if key not exist, otherwise return the exist value
+// Deprecated
+func (tree *avlTree) PutIfAbsent(key string, value interface{}) (actual interface{}, loaded bool) {
+	return putIfAbsent(tree.computeOp, key, value)
+}
+
+// Replace put only if key exist, return the old value
+// Deprecated
+func (tree *avlTree) Replace(key string, value interface{}) (old interface{}, replaced bool) {
+	return replace(tree.computeOp, key, value)
+}
+
+// CompareAndSwap put new only if value of key == old
+// Deprecated
+func (tree *avlTree) CompareAndSwap(key string, old, new interface{}) (swapped bool) {
+	return compareAndSwap(tree.computeOp, key, old, new)
+}
+
+// CompareAndDelete delete only if value of key == old
+// Deprecated
+func (tree *avlTree) CompareAndDelete(key string, old interface{}) (deleted bool) {
+	return compareAndDelete(tree.computeOp, key, old)
+}
+
+// Watch 监听键的修改，ctx 结束后关闭 channel
+// Deprecated
+func (tree *avlTree) Watch(ctx context.Context, key string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, key, false, opt)
+}
+
+// WatchPrefix 监听前缀相同的所有键的修改
+// Deprecated
+func (tree *avlTree) WatchPrefix(ctx context.Context, prefix string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, prefix, true, opt)
 }
\ No newline at end of file
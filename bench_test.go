@@ -3,6 +3,7 @@ package gomap
 import (
 	"fmt"
 	"math/rand"
+	"strconv"
 	"testing"
 )
 
@@ -292,3 +293,428 @@ func BenchmarkAVLRecursionMapRandom(b *testing.B) {
 		m.Delete(key)
 	}
 }
+
+// go test -run=none -bench="Parallel" -cpu=1,2,4,8
+// lookup take read lock, so it should scale with cpu
+func benchmarkMapGetParallel(b *testing.B, m Map) {
+	b.StopTimer()
+
+	for i := 0; i < randNum; i++ {
+		key := fmt.Sprintf("%d", i)
+		m.Put(key, key)
+	}
+
+	b.StartTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			key := strconv.Itoa(r.Intn(randNum))
+			_, _ = m.Get(key)
+		}
+	})
+}
+
+// 95% read and 5% write
+func benchmarkMapReadMostlyParallel(b *testing.B, m Map) {
+	b.StopTimer()
+
+	for i := 0; i < randNum; i++ {
+		key := fmt.Sprintf("%d", i)
+		m.Put(key, key)
+	}
+
+	b.StartTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			key := strconv.Itoa(r.Intn(randNum))
+			if r.Intn(100) < 5 {
+				m.Put(key, key)
+			} else {
+				_, _ = m.Get(key)
+			}
+		}
+	})
+}
+
+// go test -run=none -bench="GetParallelStats" -cpu=1,2,4,8
+// the same lookup with and without counting gets and compares, the gap is the cost of stats on read path
+func benchmarkMapGetParallelStats(b *testing.B, newMap func() Map) {
+	b.Run("on", func(b *testing.B) {
+		benchmarkMapGetParallel(b, newMap())
+	})
+	b.Run("off", func(b *testing.B) {
+		countReads = false
+		defer func() { countReads = true }()
+
+		benchmarkMapGetParallel(b, newMap())
+	})
+}
+
+func BenchmarkRBTMapGetParallel(b *testing.B) {
+	benchmarkMapGetParallel(b, NewMap())
+}
+
+func BenchmarkAVLMapGetParallel(b *testing.B) {
+	benchmarkMapGetParallel(b, NewAVLMap())
+}
+
+func BenchmarkRBTMapReadMostlyParallel(b *testing.B) {
+	benchmarkMapReadMostlyParallel(b, NewMap())
+}
+
+func BenchmarkAVLMapReadMostlyParallel(b *testing.B) {
+	benchmarkMapReadMostlyParallel(b, NewAVLMap())
+}
+
+func BenchmarkRBTMapGetParallelStats(b *testing.B) {
+	benchmarkMapGetParallelStats(b, NewMap)
+}
+
+func BenchmarkAVLMapGetParallelStats(b *testing.B) {
+	benchmarkMapGetParallelStats(b, NewAVLMap)
+}
+
+func BenchmarkSkipListMapGetParallelStats(b *testing.B) {
+	benchmarkMapGetParallelStats(b, NewSkipListMap)
+}
+
+func BenchmarkSkipListMapGetParallel(b *testing.B) {
+	benchmarkMapGetParallel(b, NewSkipListMap())
+}
+
+func BenchmarkSkipListMapReadMostlyParallel(b *testing.B) {
+	benchmarkMapReadMostlyParallel(b, NewSkipListMap())
+}
+
+func BenchmarkBTreeMapPut(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewBTreeMap(32)
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		xx := key + fmt.Sprintf("_%v", rand.Int63n(int64(randNum)))
+		m.Put(key, xx)
+	}
+}
+
+func BenchmarkBTreeMapDelete(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewBTreeMap(32)
+	for i := 0; i < randNum; i++ {
+		key := fmt.Sprintf("%d", i)
+		xx := key + fmt.Sprintf("_%v", i)
+		m.Put(key, xx)
+	}
+
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		m.Delete(key)
+	}
+}
+
+func BenchmarkBTreeMapGet(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewBTreeMap(32)
+	for i := 0; i < randNum; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		xx := key + fmt.Sprintf("_%v", rand.Int63n(int64(randNum)))
+		m.Put(key, xx)
+	}
+
+	//b.Logf("b-tree height:%d", m.Height())
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", -2) // can not fetch forever
+		_, _ = m.Get(key)
+	}
+}
+
+func BenchmarkTreapMapPut(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewTreapMap(32)
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		xx := key + fmt.Sprintf("_%v", rand.Int63n(int64(randNum)))
+		m.Put(key, xx)
+	}
+}
+
+func BenchmarkTreapMapDelete(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewTreapMap(32)
+	for i := 0; i < randNum; i++ {
+		key := fmt.Sprintf("%d", i)
+		xx := key + fmt.Sprintf("_%v", i)
+		m.Put(key, xx)
+	}
+
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		m.Delete(key)
+	}
+}
+
+func BenchmarkTreapMapGet(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewTreapMap(32)
+	for i := 0; i < randNum; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		xx := key + fmt.Sprintf("_%v", rand.Int63n(int64(randNum)))
+		m.Put(key, xx)
+	}
+
+	//b.Logf("treap height:%d", m.Height())
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", -2) // can not fetch forever
+		_, _ = m.Get(key)
+	}
+}
+
+// go test -run=none -bench="Zipf"
+// zipf skewed key, few hot keys get most of access, splay tree keep them near root
+func benchmarkMapZipfGet(b *testing.B, m Map) {
+	b.StopTimer()
+
+	keys := make([]string, randNum)
+	for i := 0; i < randNum; i++ {
+		keys[i] = fmt.Sprintf("%d", i)
+		m.Put(keys[i], keys[i])
+	}
+
+	// hot keys are scattered, not the min or max
+	r := rand.New(rand.NewSource(int64(randNum)))
+	r.Shuffle(len(keys), func(i, j int) {
+		keys[i], keys[j] = keys[j], keys[i]
+	})
+	z := rand.NewZipf(r, 1.1, 1, uint64(randNum-1))
+
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = m.Get(keys[z.Uint64()])
+	}
+}
+
+func BenchmarkRBTMapZipfGet(b *testing.B) {
+	benchmarkMapZipfGet(b, NewMap())
+}
+
+func BenchmarkAVLMapZipfGet(b *testing.B) {
+	benchmarkMapZipfGet(b, NewAVLMap())
+}
+
+func BenchmarkSplayMapZipfGet(b *testing.B) {
+	benchmarkMapZipfGet(b, NewSplayMap())
+}
+
+// go test -run=none -bench="RBTMap|LLRBMap"
+// 2-3 tree llrb and 2-3-4 tree rbt on the same workload
+func BenchmarkLLRBMapPut(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewLLRBMap()
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		xx := key + fmt.Sprintf("_%v", rand.Int63n(int64(randNum)))
+		m.Put(key, xx)
+	}
+}
+
+func BenchmarkLLRBMapDelete(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewLLRBMap()
+	for i := 0; i < randNum; i++ {
+		key := fmt.Sprintf("%d", i)
+		xx := key + fmt.Sprintf("_%v", i)
+		m.Put(key, xx)
+	}
+
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		m.Delete(key)
+	}
+}
+
+func BenchmarkLLRBMapGet(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewLLRBMap()
+	for i := 0; i < randNum; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		xx := key + fmt.Sprintf("_%v", rand.Int63n(int64(randNum)))
+		m.Put(key, xx)
+	}
+
+	//b.Logf("llrb tree height:%d", m.Height())
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", -2) // can not fetch forever
+		_, _ = m.Get(key)
+	}
+}
+
+func BenchmarkLLRBMapRandom(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewLLRBMap()
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		xx := key + fmt.Sprintf("_%v", rand.Int63n(int64(randNum)))
+		m.Put(key, xx)
+
+		key = fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		m.Delete(key)
+	}
+}
+
+func BenchmarkLLRBMapGetParallel(b *testing.B) {
+	benchmarkMapGetParallel(b, NewLLRBMap())
+}
+
+func BenchmarkLLRBMapReadMostlyParallel(b *testing.B) {
+	benchmarkMapReadMostlyParallel(b, NewLLRBMap())
+}
+
+func BenchmarkLLRBMapZipfGet(b *testing.B) {
+	benchmarkMapZipfGet(b, NewLLRBMap())
+}
+
+func BenchmarkScapegoatMapPut(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewScapegoatMap(0.7)
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		xx := key + fmt.Sprintf("_%v", rand.Int63n(int64(randNum)))
+		m.Put(key, xx)
+	}
+}
+
+func BenchmarkScapegoatMapDelete(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewScapegoatMap(0.7)
+	for i := 0; i < randNum; i++ {
+		key := fmt.Sprintf("%d", i)
+		xx := key + fmt.Sprintf("_%v", i)
+		m.Put(key, xx)
+	}
+
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		m.Delete(key)
+	}
+}
+
+func BenchmarkScapegoatMapGet(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewScapegoatMap(0.7)
+	for i := 0; i < randNum; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		xx := key + fmt.Sprintf("_%v", rand.Int63n(int64(randNum)))
+		m.Put(key, xx)
+	}
+
+	//b.Logf("scapegoat tree height:%d", m.Height())
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", -2) // can not fetch forever
+		_, _ = m.Get(key)
+	}
+}
+
+func BenchmarkWBTMapPut(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewWBTMap()
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		xx := key + fmt.Sprintf("_%v", rand.Int63n(int64(randNum)))
+		m.Put(key, xx)
+	}
+}
+
+func BenchmarkWBTMapDelete(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewWBTMap()
+	for i := 0; i < randNum; i++ {
+		key := fmt.Sprintf("%d", i)
+		xx := key + fmt.Sprintf("_%v", i)
+		m.Put(key, xx)
+	}
+
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		m.Delete(key)
+	}
+}
+
+func BenchmarkWBTMapGet(b *testing.B) {
+	b.StopTimer()
+
+	rand.Seed(int64(randNum))
+
+	m := NewWBTMap()
+	for i := 0; i < randNum; i++ {
+		key := fmt.Sprintf("%d", rand.Int63n(int64(randNum)))
+		xx := key + fmt.Sprintf("_%v", rand.Int63n(int64(randNum)))
+		m.Put(key, xx)
+	}
+
+	//b.Logf("wbt tree height:%d", m.Height())
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("%d", -2) // can not fetch forever
+		_, _ = m.Get(key)
+	}
+}
@@ -0,0 +1,1347 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// default min degree of b-tree, node hold 31 to 63 key pairs
+const bTreeDefaultDegree = 32
+
+// which item to remove from b-tree
+const (
+	bTreeRemoveItem = iota // remove the key
+	bTreeRemoveMin         // remove min key
+	bTreeRemoveMax         // remove max key
+)
+
+// B-Tree, key pairs store inline in node, a node of tens of key pairs take few cache lines and one heap object
+// so it is much less pointers than binary tree for GC to scan
+type bTree struct {
+	modCount     int64      // num of add or delete key, iterator use it to fail fast
+	c            comparator // tree key compare
+	root         *bTreeNode // tree root
+	len          int64      // tree key pairs num
+	degree       int        // min degree t, node except root has t-1 to 2t-1 key pairs
+	gen          uint64     // node gen less than it is shared by snapshot, copy before change
+	watch        *watchHub  // subscribers of change, nil until first watch
+	stats        mapStats   // live counters
+	sync.RWMutex            // lock for concurrent safe, read lock for lookup
+}
+
+// key pairs in node
+type bTreeItem struct {
+	k string      // key
+	v interface{} // value
+}
+
+type bTreeNode struct {
+	items    []bTreeItem  // key pairs sorted
+	children []*bTreeNode // len(items)+1 children, nil for leaf
+	size     int64        // key pairs num of the sub tree
+	gen      uint64       // gen of tree when node created or copied
+}
+
+// NewBTreeMap new a b-tree map, degree is min degree t, node hold t-1 to 2t-1 key pairs
+// degree less than 2 use default 32
+func NewBTreeMap(degree int) Map {
+	if degree < 2 {
+		degree = bTreeDefaultDegree
+	}
+
+	t := new(bTree)
+	t.c = comparatorDefault
+	t.degree = degree
+	return t
+}
+
+func (tree *bTree) minItems() int {
+	return tree.degree - 1
+}
+
+func (tree *bTree) maxItems() int {
+	return 2*tree.degree - 1
+}
+
+// new empty node, one more room for the key pairs which make it split
+func (tree *bTree) newNode(leaf bool) *bTreeNode {
+	node := &bTreeNode{
+		items: make([]bTreeItem, 0, tree.maxItems()+1),
+		gen:   tree.gen,
+	}
+
+	if !leaf {
+		node.children = make([]*bTreeNode, 0, tree.maxItems()+2)
+	}
+
+	return node
+}
+
+// node shared by snapshot, copy it for tree to change, caller should put the copy to parent
+func (tree *bTree) own(node *bTreeNode) *bTreeNode {
+	if node == nil || node.gen == tree.gen {
+		return node
+	}
+
+	n := tree.newNode(node.leaf())
+	n.items = append(n.items, node.items...)
+	if !node.leaf() {
+		n.children = append(n.children, node.children...)
+	}
+	n.size = node.size
+	return n
+}
+
+// copy child i if shared, parent should be owned first, so it is path copying
+func (tree *bTree) mutableChild(node *bTreeNode, i int) *bTreeNode {
+	child := tree.own(node.children[i])
+	node.children[i] = child
+	return child
+}
+
+func (node *bTreeNode) leaf() bool {
+	return len(node.children) == 0
+}
+
+// cal sub tree size
+func (node *bTreeNode) treeSize() int64 {
+	if node == nil {
+		return 0
+	}
+
+	return node.size
+}
+
+// binary search key in node, return index of key if found, otherwise index of the first key greater than key
+// num of compare add to cmp
+func (node *bTreeNode) search(c comparator, key string, cmp *int64) (int, bool) {
+	lo, hi := 0, len(node.items)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		*cmp++
+		r := c(node.items[mid].k, key)
+		if r == 0 {
+			return mid, true
+		} else if r < 0 {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo, false
+}
+
+func (node *bTreeNode) insertItem(i int, item bTreeItem) {
+	node.items = append(node.items, bTreeItem{})
+	copy(node.items[i+1:], node.items[i:])
+	node.items[i] = item
+}
+
+func (node *bTreeNode) removeItem(i int) bTreeItem {
+	item := node.items[i]
+	copy(node.items[i:], node.items[i+1:])
+
+	// clear the tail so GC can free the key pairs
+	node.items[len(node.items)-1] = bTreeItem{}
+	node.items = node.items[:len(node.items)-1]
+	return item
+}
+
+func (node *bTreeNode) insertChild(i int, child *bTreeNode) {
+	node.children = append(node.children, nil)
+	copy(node.children[i+1:], node.children[i:])
+	node.children[i] = child
+}
+
+func (node *bTreeNode) removeChild(i int) *bTreeNode {
+	child := node.children[i]
+	copy(node.children[i:], node.children[i+1:])
+	node.children[len(node.children)-1] = nil
+	node.children = node.children[:len(node.children)-1]
+	return child
+}
+
+// split full node at index t, node keep t key pairs, return the middle key pairs and the new right node
+func (tree *bTree) split(node *bTreeNode) (bTreeItem, *bTreeNode) {
+	t := tree.degree
+	mid := node.items[t]
+
+	right := tree.newNode(node.leaf())
+	right.items = append(right.items, node.items[t+1:]...)
+	for i := t; i < len(node.items); i++ {
+		node.items[i] = bTreeItem{}
+	}
+	node.items = node.items[:t]
+
+	right.size = int64(len(right.items))
+	if !node.leaf() {
+		right.children = append(right.children, node.children[t+1:]...)
+		for i := t + 1; i < len(node.children); i++ {
+			node.children[i] = nil
+		}
+		node.children = node.children[:t+1]
+
+		for _, child := range right.children {
+			right.size += child.size
+		}
+	}
+
+	node.size -= right.size + 1
+	return mid, right
+}
+
+func (tree *bTree) Put(key string, value interface{}) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.put(key, value)
+}
+
+// put key pairs without lock, caller should lock first
+// node split after insert rather than before, so update value never change the shape and iterator still work
+func (tree *bTree) put(key string, value interface{}) {
+	if tree.root == nil {
+		tree.root = tree.newNode(true)
+	} else {
+		tree.root = tree.own(tree.root)
+	}
+
+	var n int64
+	old, replaced := tree.insert(tree.root, key, value, &n)
+	tree.stats.compare(key, n)
+	atomic.AddInt64(&tree.stats.puts, 1)
+
+	if replaced {
+		tree.watch.put(key, value, old, true)
+		return
+	}
+
+	// root is full, tree grow up
+	if len(tree.root.items) > tree.maxItems() {
+		left := tree.root
+		mid, right := tree.split(left)
+
+		tree.root = tree.newNode(false)
+		tree.root.items = append(tree.root.items, mid)
+		tree.root.children = append(tree.root.children, left, right)
+		tree.root.size = left.size + right.size + 1
+	}
+
+	tree.len++
+	atomic.AddInt64(&tree.modCount, 1)
+	tree.watch.put(key, value, nil, false)
+}
+
+// insert key pairs into sub tree of node, node should be owned, child over full is split into node
+func (tree *bTree) insert(node *bTreeNode, key string, value interface{}, n *int64) (old interface{}, replaced bool) {
+	i, found := node.search(tree.c, key, n)
+	if found {
+		old = node.items[i].v
+		node.items[i].v = value
+		return old, true
+	}
+
+	if node.leaf() {
+		node.insertItem(i, bTreeItem{k: key, v: value})
+		node.size++
+		return nil, false
+	}
+
+	child := tree.mutableChild(node, i)
+	old, replaced = tree.insert(child, key, value, n)
+	if replaced {
+		return
+	}
+
+	node.size++
+	if len(child.items) > tree.maxItems() {
+		mid, right := tree.split(child)
+		node.insertItem(i, mid)
+		node.insertChild(i+1, right)
+	}
+
+	return nil, false
+}
+
+func (tree *bTree) Delete(key string) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.deleteKey(key)
+}
+
+// delete key without lock, caller should lock first
+func (tree *bTree) deleteKey(key string) (value interface{}, exist bool) {
+	_, value, exist = tree.delete(key, bTreeRemoveItem)
+	return
+}
+
+// delete key, min or max key pairs by typ, without lock
+func (tree *bTree) delete(key string, typ int) (k string, value interface{}, exist bool) {
+	if tree.root == nil {
+		return
+	}
+
+	tree.root = tree.own(tree.root)
+
+	var n int64
+	item, ok := tree.remove(tree.root, key, typ, &n)
+	tree.stats.compare(key, n)
+	if !ok {
+		return
+	}
+
+	// root is empty, tree grow down
+	if len(tree.root.items) == 0 {
+		if tree.root.leaf() {
+			tree.root = nil
+		} else {
+			tree.root = tree.root.children[0]
+		}
+	}
+
+	tree.len--
+	atomic.AddInt64(&tree.modCount, 1)
+	atomic.AddInt64(&tree.stats.deletes, 1)
+	tree.watch.delete(item.k, item.v)
+	return item.k, item.v, true
+}
+
+// remove key pairs from sub tree of node, node should be owned, child less than t-1 key pairs is fixed by node
+func (tree *bTree) remove(node *bTreeNode, key string, typ int, n *int64) (bTreeItem, bool) {
+	var i int
+	var found bool
+	switch typ {
+	case bTreeRemoveMin:
+		i, found = 0, node.leaf()
+	case bTreeRemoveMax:
+		i, found = len(node.items), false
+		if node.leaf() {
+			i, found = len(node.items)-1, true
+		}
+	default:
+		i, found = node.search(tree.c, key, n)
+	}
+
+	if node.leaf() {
+		if !found {
+			return bTreeItem{}, false
+		}
+
+		node.size--
+		return node.removeItem(i), true
+	}
+
+	var item bTreeItem
+	child := tree.mutableChild(node, i)
+	if found {
+		// key in inner node, replace by the max key pairs of left child
+		item = node.items[i]
+		node.items[i], _ = tree.remove(child, "", bTreeRemoveMax, n)
+	} else {
+		var ok bool
+		item, ok = tree.remove(child, key, typ, n)
+		if !ok {
+			return item, false
+		}
+	}
+
+	node.size--
+	if len(child.items) < tree.minItems() {
+		tree.fixChild(node, i)
+	}
+
+	return item, true
+}
+
+// child i has t-2 key pairs, borrow one from sibling, or merge with sibling when both are small
+func (tree *bTree) fixChild(node *bTreeNode, i int) {
+	if i > 0 && len(node.children[i-1].items) > tree.minItems() {
+		// borrow from left sibling, key pairs move right through node
+		atomic.AddInt64(&tree.stats.rightRotations, 1)
+		child := node.children[i]
+		left := tree.mutableChild(node, i-1)
+
+		child.insertItem(0, node.items[i-1])
+		node.items[i-1] = left.removeItem(len(left.items) - 1)
+		delta := int64(1)
+		if !left.leaf() {
+			moved := left.removeChild(len(left.children) - 1)
+			child.insertChild(0, moved)
+			delta += moved.size
+		}
+
+		left.size -= delta
+		child.size += delta
+		return
+	}
+
+	if i < len(node.items) && len(node.children[i+1].items) > tree.minItems() {
+		// borrow from right sibling, key pairs move left through node
+		atomic.AddInt64(&tree.stats.leftRotations, 1)
+		child := node.children[i]
+		right := tree.mutableChild(node, i+1)
+
+		child.items = append(child.items, node.items[i])
+		node.items[i] = right.removeItem(0)
+		delta := int64(1)
+		if !right.leaf() {
+			moved := right.removeChild(0)
+			child.children = append(child.children, moved)
+			delta += moved.size
+		}
+
+		right.size -= delta
+		child.size += delta
+		return
+	}
+
+	// merge child with right sibling, last child merge with left sibling
+	if i == len(node.items) {
+		i--
+	}
+
+	left := tree.mutableChild(node, i)
+	right := node.removeChild(i + 1)
+	left.items = append(left.items, node.removeItem(i))
+	left.items = append(left.items, right.items...)
+	left.children = append(left.children, right.children...)
+	left.size += right.size + 1
+}
+
+// find key in tree, without lock
+func (tree *bTree) find(key string) (*bTreeNode, int) {
+	var n int64
+	defer func() {
+		tree.stats.compare(key, n)
+	}()
+
+	node := tree.root
+	for node != nil {
+		i, found := node.search(tree.c, key, &n)
+		if found {
+			return node, i
+		}
+
+		if node.leaf() {
+			break
+		}
+		node = node.children[i]
+	}
+
+	return nil, 0
+}
+
+// MinKey find min key pairs
+func (tree *bTree) MinKey() (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	item := tree.root.min()
+	return item.k, item.v, true
+}
+
+// most left key pairs of sub tree
+func (node *bTreeNode) min() bTreeItem {
+	for !node.leaf() {
+		node = node.children[0]
+	}
+
+	return node.items[0]
+}
+
+// MaxKey find max key pairs
+func (tree *bTree) MaxKey() (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	item := tree.root.max()
+	return item.k, item.v, true
+}
+
+// most right key pairs of sub tree
+func (node *bTreeNode) max() bTreeItem {
+	for !node.leaf() {
+		node = node.children[len(node.children)-1]
+	}
+
+	return node.items[len(node.items)-1]
+}
+
+// Floor find the greatest key pairs less than or equal to key
+func (tree *bTree) Floor(key string) (floorKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.floor(key, true)
+}
+
+// Ceiling find the least key pairs greater than or equal to key
+func (tree *bTree) Ceiling(key string) (ceilingKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.ceiling(key, true)
+}
+
+// Lower find the greatest key pairs strictly less than key
+func (tree *bTree) Lower(key string) (lowerKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.floor(key, false)
+}
+
+// Higher find the least key pairs strictly greater than key
+func (tree *bTree) Higher(key string) (higherKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.ceiling(key, false)
+}
+
+// the greatest key pairs less than key, less than or equal to key when inclusive
+func (tree *bTree) floor(key string, inclusive bool) (k string, value interface{}, exist bool) {
+	var n int64
+	node := tree.root
+	for node != nil {
+		i, found := node.search(tree.c, key, &n)
+		if found && inclusive {
+			k, value, exist = node.items[i].k, node.items[i].v, true
+			break
+		}
+
+		// key pairs before i are less than key, greater ones are in child i
+		if i > 0 {
+			k, value, exist = node.items[i-1].k, node.items[i-1].v, true
+		}
+
+		if node.leaf() {
+			break
+		}
+		node = node.children[i]
+	}
+
+	tree.stats.compare(key, n)
+	return
+}
+
+// the least key pairs greater than key, greater than or equal to key when inclusive
+func (tree *bTree) ceiling(key string, inclusive bool) (k string, value interface{}, exist bool) {
+	var n int64
+	node := tree.root
+	for node != nil {
+		i, found := node.search(tree.c, key, &n)
+		if found {
+			if inclusive {
+				k, value, exist = node.items[i].k, node.items[i].v, true
+				break
+			}
+			i++
+		}
+
+		// key pairs from i are greater than key, less ones are in child i
+		if i < len(node.items) {
+			k, value, exist = node.items[i].k, node.items[i].v, true
+		}
+
+		if node.leaf() {
+			break
+		}
+		node = node.children[i]
+	}
+
+	tree.stats.compare(key, n)
+	return
+}
+
+// Rank num of keys strictly less than key
+func (tree *bTree) Rank(key string) int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	var rank, n int64
+	node := tree.root
+	for node != nil {
+		i, found := node.search(tree.c, key, &n)
+
+		// key pairs before i and children before i are less than key
+		rank += int64(i)
+		if node.leaf() {
+			break
+		}
+
+		for _, child := range node.children[:i] {
+			rank += child.size
+		}
+
+		if found {
+			rank += node.children[i].size
+			break
+		}
+		node = node.children[i]
+	}
+
+	tree.stats.compare(key, n)
+	return rank
+}
+
+// Select find the i-th smallest key pairs, i start from 0
+func (tree *bTree) Select(i int64) (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if i < 0 || i >= tree.root.treeSize() {
+		return
+	}
+
+	node := tree.root
+	for !node.leaf() {
+		j := 0
+		for ; j < len(node.items); j++ {
+			size := node.children[j].size
+			if i < size {
+				break
+			}
+
+			// skip child j and key pairs j
+			if i == size {
+				return node.items[j].k, node.items[j].v, true
+			}
+			i -= size + 1
+		}
+
+		node = node.children[j]
+	}
+
+	return node.items[i].k, node.items[i].v, true
+}
+
+func (tree *bTree) Get(key string) (value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
+
+	return tree.get(key)
+}
+
+// get value of key without lock, caller should lock first
+func (tree *bTree) get(key string) (value interface{}, exist bool) {
+	if node, i := tree.find(key); node != nil {
+		return node.items[i].v, true
+	}
+
+	return
+}
+
+func (tree *bTree) Contains(key string) (exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
+
+	node, _ := tree.find(key)
+	return node != nil
+}
+
+func (tree *bTree) Len() int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.len
+}
+
+func (tree *bTree) GetInt(key string) (value int, exist bool, err error) {
+	return getInt(tree.Get, key)
+}
+
+func (tree *bTree) GetInt64(key string) (value int64, exist bool, err error) {
+	return getInt64(tree.Get, key)
+}
+
+func (tree *bTree) GetString(key string) (value string, exist bool, err error) {
+	return getString(tree.Get, key)
+}
+
+func (tree *bTree) GetFloat64(key string) (value float64, exist bool, err error) {
+	return getFloat64(tree.Get, key)
+}
+
+func (tree *bTree) GetBytes(key string) (value []byte, exist bool, err error) {
+	return getBytes(tree.Get, key)
+}
+
+func (tree *bTree) KeySortedList() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	if tree.root != nil {
+		tree.root.ascend(tree.c, nil, nil, func(key string, value interface{}) bool {
+			keyList = append(keyList, key)
+			return true
+		})
+	}
+
+	return keyList
+}
+
+func (tree *bTree) KeySortedListDesc() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	if tree.root != nil {
+		tree.root.descend(func(key string, value interface{}) bool {
+			keyList = append(keyList, key)
+			return true
+		})
+	}
+
+	return keyList
+}
+
+// walk ge <= key < lt in sub tree, bound nil means no bound
+// return false when fn stop or reach lt
+func (node *bTreeNode) ascend(c comparator, ge, lt *string, fn WalkFunc) bool {
+	i := 0
+	if ge != nil {
+		var n int64
+		i, _ = node.search(c, *ge, &n)
+	}
+
+	for ; i < len(node.items); i++ {
+		if !node.leaf() && !node.children[i].ascend(c, ge, lt, fn) {
+			return false
+		}
+
+		if lt != nil && c(node.items[i].k, *lt) >= 0 {
+			return false
+		}
+
+		if !fn(node.items[i].k, node.items[i].v) {
+			return false
+		}
+	}
+
+	if node.leaf() {
+		return true
+	}
+
+	return node.children[i].ascend(c, ge, lt, fn)
+}
+
+// walk sub tree from max to min
+// return false if fn stop walking
+func (node *bTreeNode) descend(fn WalkFunc) bool {
+	for i := len(node.items) - 1; i >= 0; i-- {
+		if !node.leaf() && !node.children[i+1].descend(fn) {
+			return false
+		}
+
+		if !fn(node.items[i].k, node.items[i].v) {
+			return false
+		}
+	}
+
+	if node.leaf() {
+		return true
+	}
+
+	return node.children[0].descend(fn)
+}
+
+// Check key pairs sorted, every node has t-1 to 2t-1 key pairs, all leaves at the same depth, size is right
+func (tree *bTree) Check() bool {
+	if tree == nil || tree.root == nil {
+		return true
+	}
+
+	if len(tree.root.items) == 0 {
+		fmt.Println("root is empty")
+		return false
+	}
+
+	if tree.root.size != tree.len {
+		fmt.Printf("root size %d != len %d\n", tree.root.size, tree.len)
+		return false
+	}
+
+	leafDepth := -1
+	return tree.isBTree(tree.root, nil, nil, 0, &leafDepth)
+}
+
+// check sub tree, all keys in (lo, hi), nil means no bound
+func (tree *bTree) isBTree(node *bTreeNode, lo, hi *string, depth int, leafDepth *int) bool {
+	if len(node.items) > tree.maxItems() || (node != tree.root && len(node.items) < tree.minItems()) {
+		fmt.Printf("node has %d key pairs, out of [%d, %d]\n", len(node.items), tree.minItems(), tree.maxItems())
+		return false
+	}
+
+	for i, item := range node.items {
+		if (i > 0 && tree.c(node.items[i-1].k, item.k) >= 0) || (lo != nil && tree.c(*lo, item.k) >= 0) || (hi != nil && tree.c(item.k, *hi) >= 0) {
+			fmt.Printf("key %s is not sorted in node\n", item.k)
+			return false
+		}
+	}
+
+	if node.leaf() {
+		if *leafDepth == -1 {
+			*leafDepth = depth
+		}
+
+		if depth != *leafDepth {
+			fmt.Printf("leaf depth %d != %d\n", depth, *leafDepth)
+			return false
+		}
+
+		if node.size != int64(len(node.items)) {
+			fmt.Printf("leaf size %d != %d\n", node.size, len(node.items))
+			return false
+		}
+
+		return true
+	}
+
+	if len(node.children) != len(node.items)+1 {
+		fmt.Printf("node has %d key pairs but %d children\n", len(node.items), len(node.children))
+		return false
+	}
+
+	size := int64(len(node.items))
+	for i, child := range node.children {
+		childLo, childHi := lo, hi
+		if i > 0 {
+			childLo = &node.items[i-1].k
+		}
+		if i < len(node.items) {
+			childHi = &node.items[i].k
+		}
+
+		if !tree.isBTree(child, childLo, childHi, depth+1, leafDepth) {
+			return false
+		}
+		size += child.size
+	}
+
+	if node.size != size {
+		fmt.Printf("size %d != %d\n", node.size, size)
+		return false
+	}
+
+	return true
+}
+
+// Height levels of node, all leaves at the same level
+func (tree *bTree) Height() int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.height()
+}
+
+// height without lock, caller should lock first
+func (tree *bTree) height() int64 {
+	var h int64
+	for node := tree.root; node != nil; h++ {
+		if node.leaf() {
+			node = nil
+		} else {
+			node = node.children[0]
+		}
+	}
+
+	return h
+}
+
+// Stats live counters, b-tree borrow key pairs from sibling is count as rotation
+func (tree *bTree) Stats() Stats {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := tree.stats.load()
+	s.Len = tree.len
+	s.Height = tree.height()
+	s.HeightBound = bTreeHeightBound(s.Len, tree.degree)
+	return s
+}
+
+func (tree *bTree) KeyList() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	iterator := tree.iterator()
+	for iterator.HasNext() {
+		k, _ := iterator.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList
+}
+
+// Iterator node by node in layer order, key pairs of a node are sorted
+func (tree *bTree) Iterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.iterator()
+}
+
+// layer order iterator, without lock
+func (tree *bTree) iterator() MapIterator {
+	it := new(bTreeLayerIterator)
+	it.bind(&tree.modCount)
+	if tree.root != nil {
+		it.nodes = append(it.nodes, tree.root)
+	}
+	return it
+}
+
+// SafeIterator sorted iterator safe under concurrent write, snapshot mode is consistent, weak mode find next key every step
+func (tree *bTree) SafeIterator(mode IteratorMode) MapIterator {
+	return newSafeIterator(tree, mode)
+}
+
+// AscendIterator iterator sorted by key, from min to max
+func (tree *bTree) AscendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it := new(bTreeIterator)
+	it.bind(&tree.modCount)
+	it.pushPath(tree.root)
+	return it
+}
+
+// DescendIterator iterator sorted by key, from max to min
+func (tree *bTree) DescendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it := &bTreeIterator{desc: true}
+	it.bind(&tree.modCount)
+	it.pushPath(tree.root)
+	return it
+}
+
+// Range iterator key between from and to, sorted by key
+func (tree *bTree) Range(from, to string, opt RangeOption) MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.rangeIterator(from, to, opt)
+}
+
+// range iterator without lock
+func (tree *bTree) rangeIterator(from, to string, opt RangeOption) MapIterator {
+	it := new(bTreeIterator)
+	it.bind(&tree.modCount)
+
+	c := tree.c
+	if !opt.ToUnbounded {
+		it.within = func(key string) bool {
+			cmp := c(key, to)
+			return cmp < 0 || (cmp == 0 && !opt.ToExclusive)
+		}
+	}
+
+	if opt.FromUnbounded {
+		it.pushPath(tree.root)
+	} else {
+		it.seek(tree.root, c, from, opt.FromExclusive)
+	}
+
+	return it
+}
+
+// Cursor bidirectional cursor, before min key at first
+func (tree *bTree) Cursor() Cursor {
+	return newCursor(tree)
+}
+
+// PrefixIterator iterator key with prefix, sorted by key
+func (tree *bTree) PrefixIterator(prefix string) (MapIterator, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return it, nil
+}
+
+// KeysWithPrefix key with prefix out to list sorted
+func (tree *bTree) KeysWithPrefix(prefix string) ([]string, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keyList := make([]string, 0)
+	for it.HasNext() {
+		k, _ := it.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList, nil
+}
+
+func (tree *bTree) prefixIterator(prefix string) (*bTreeIterator, error) {
+	if !isDefaultComparator(tree.c) {
+		return nil, ErrPrefixComparator
+	}
+
+	it := &bTreeIterator{
+		within: func(key string) bool {
+			return strings.HasPrefix(key, prefix)
+		},
+	}
+	it.bind(&tree.modCount)
+	it.seek(tree.root, tree.c, prefix, false)
+	return it, nil
+}
+
+// DeleteRange delete keys which from <= key <= to, return num of deleted keys
+func (tree *bTree) DeleteRange(from, to string) int64 {
+	return deleteRange(tree, from, to)
+}
+
+// PopMin find min key pairs and delete it
+func (tree *bTree) PopMin() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	return tree.delete("", bTreeRemoveMin)
+}
+
+// PopMax find max key pairs and delete it
+func (tree *bTree) PopMax() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	return tree.delete("", bTreeRemoveMax)
+}
+
+// Ascend walk all key pairs from min to max, stop when fn return false
+func (tree *bTree) Ascend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		tree.root.ascend(tree.c, nil, nil, fn)
+	}
+}
+
+// Descend walk all key pairs from max to min, stop when fn return false
+func (tree *bTree) Descend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		tree.root.descend(fn)
+	}
+}
+
+// AscendGreaterOrEqual walk key pairs which pivot <= key, stop when fn return false
+func (tree *bTree) AscendGreaterOrEqual(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		tree.root.ascend(tree.c, &pivot, nil, fn)
+	}
+}
+
+// AscendLessThan walk key pairs which key < pivot, stop when fn return false
+func (tree *bTree) AscendLessThan(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		tree.root.ascend(tree.c, nil, &pivot, fn)
+	}
+}
+
+// AscendRange walk key pairs which greaterOrEqual <= key < lessThan, stop when fn return false
+func (tree *bTree) AscendRange(greaterOrEqual, lessThan string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		tree.root.ascend(tree.c, &greaterOrEqual, &lessThan, fn)
+	}
+}
+
+func (tree *bTree) SetComparator(c comparator) Map {
+	tree.Lock()
+	defer tree.Unlock()
+	if tree.len == 0 {
+		tree.c = c
+	}
+
+	return tree
+}
+
+// Snapshot read only view of tree now, cost O(1)
+// all nodes now are shared with snapshot, later write copy the path rather than change them
+func (tree *bTree) Snapshot() ReadOnlyMap {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.gen++
+	return readOnlyMap{&bTree{
+		c:      tree.c,
+		root:   tree.root,
+		len:    tree.len,
+		degree: tree.degree,
+	}}
+}
+
+// Begin transaction, all writes apply on commit under one lock
+func (tree *bTree) Begin() Txn {
+	return beginTxn(tree)
+}
+
+// Compute find key first, then put or delete under the same lock
+func (tree *bTree) Compute(key string, fn ComputeFunc) (value interface{}, exist bool) {
+	return tree.computeOp(key, fn.op())
+}
+
+// compute with lock, find key first, then put or delete, nothing write when fn leave the key unchanged
+// it descend twice when fn write, O(logN) each: find walk nodes read only, then put or delete walk again from root
+// to own the nodes shared with snapshot and split or merge them on the way, so write can not stop at the found node
+func (tree *bTree) computeOp(key string, fn computeOpFunc) (value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	var old interface{}
+	node, i := tree.find(key)
+	if node != nil {
+		old = node.items[i].v
+	}
+
+	value, op := fn(old, node != nil)
+	switch op {
+	case computePut:
+		tree.put(key, value)
+		return value, true
+	case computeDelete:
+		if node != nil {
+			tree.deleteKey(key)
+		}
+
+		return nil, false
+	}
+
+	return old, node != nil
+}
+
+// PutIfAbsent put if key not exist, otherwise return the exist value
+func (tree *bTree) PutIfAbsent(key string, value interface{}) (actual interface{}, loaded bool) {
+	return putIfAbsent(tree.computeOp, key, value)
+}
+
+// Replace put only if key exist, return the old value
+func (tree *bTree) Replace(key string, value interface{}) (old interface{}, replaced bool) {
+	return replace(tree.computeOp, key, value)
+}
+
+// CompareAndSwap put new only if value of key == old
+func (tree *bTree) CompareAndSwap(key string, old, new interface{}) (swapped bool) {
+	return compareAndSwap(tree.computeOp, key, old, new)
+}
+
+// CompareAndDelete delete only if value of key == old
+func (tree *bTree) CompareAndDelete(key string, old interface{}) (deleted bool) {
+	return compareAndDelete(tree.computeOp, key, old)
+}
+
+// Watch watch put and delete of key, channel closed after ctx done
+func (tree *bTree) Watch(ctx context.Context, key string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, key, false, opt)
+}
+
+// WatchPrefix watch put and delete of keys with prefix
+func (tree *bTree) WatchPrefix(ctx context.Context, prefix string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, prefix, true, opt)
+}
+
+// position in node, next key pairs is items[i]
+type bTreeFrame struct {
+	node *bTreeNode
+	i    int
+}
+
+// use stack implement sorted iterator of b-tree, only keep one path of tree
+type bTreeIterator struct {
+	stack    []bTreeFrame
+	desc     bool                  // iterator from max to min
+	within   func(key string) bool // key still not reach the upper bound, nil means no upper bound
+	modGuard                       // fail fast when map modified
+}
+
+// push the most left path, the most right path when desc
+func (it *bTreeIterator) pushPath(node *bTreeNode) {
+	for node != nil {
+		if it.desc {
+			it.stack = append(it.stack, bTreeFrame{node: node, i: len(node.items) - 1})
+		} else {
+			it.stack = append(it.stack, bTreeFrame{node: node})
+		}
+
+		if node.leaf() {
+			break
+		}
+
+		if it.desc {
+			node = node.children[len(node.children)-1]
+		} else {
+			node = node.children[0]
+		}
+	}
+
+	it.trim()
+}
+
+// find the first key pairs greater than or equal to key, greater than key when exclusive, only support asc
+func (it *bTreeIterator) seek(node *bTreeNode, c comparator, key string, exclusive bool) {
+	var n int64
+	for node != nil {
+		i, found := node.search(c, key, &n)
+		if found && !exclusive {
+			it.stack = append(it.stack, bTreeFrame{node: node, i: i})
+			break
+		}
+
+		// child i is between key and items[i]
+		if found {
+			i++
+		}
+		it.stack = append(it.stack, bTreeFrame{node: node, i: i})
+
+		if node.leaf() {
+			break
+		}
+		node = node.children[i]
+	}
+
+	it.trim()
+}
+
+// pop node which all key pairs are out
+func (it *bTreeIterator) trim() {
+	for len(it.stack) > 0 {
+		top := it.stack[len(it.stack)-1]
+		if top.i >= 0 && top.i < len(top.node.items) {
+			return
+		}
+
+		it.stack[len(it.stack)-1] = bTreeFrame{}
+		it.stack = it.stack[:len(it.stack)-1]
+	}
+}
+
+// HasNext stack top still less than upper bound
+func (it *bTreeIterator) HasNext() bool {
+	if !it.check() || len(it.stack) == 0 {
+		return false
+	}
+
+	if it.within == nil {
+		return true
+	}
+
+	top := it.stack[len(it.stack)-1]
+	return it.within(top.node.items[top.i].k)
+}
+
+func (it *bTreeIterator) Next() (key string, value interface{}) {
+	// map modified, node in stack may be changed, Err will return ErrConcurrentModification
+	if !it.check() {
+		return
+	}
+
+	// panic here
+	if !it.HasNext() {
+		panic("Next() empty")
+	}
+
+	top := &it.stack[len(it.stack)-1]
+	node, i := top.node, top.i
+	key, value = node.items[i].k, node.items[i].v
+
+	// next key pairs are in the child next to it
+	if it.desc {
+		top.i--
+	} else {
+		top.i++
+		i++
+	}
+
+	if node.leaf() {
+		it.trim()
+	} else {
+		it.pushPath(node.children[i])
+	}
+
+	return key, value
+}
+
+// use queue implement layer order iterator of b-tree
+type bTreeLayerIterator struct {
+	nodes    []*bTreeNode // node queue, head node is iterating
+	i        int          // next key pairs of head node
+	modGuard              // fail fast when map modified
+}
+
+// HasNext has next, queue size > 0
+func (it *bTreeLayerIterator) HasNext() bool {
+	return it.check() && len(it.nodes) > 0
+}
+
+func (it *bTreeLayerIterator) Next() (key string, value interface{}) {
+	// map modified, Err will return ErrConcurrentModification
+	if !it.check() {
+		return
+	}
+
+	// panic here
+	if len(it.nodes) == 0 {
+		panic("Next() empty")
+	}
+
+	node := it.nodes[0]
+	key, value = node.items[it.i].k, node.items[it.i].v
+	it.i++
+
+	// all key pairs of head node are out, children join the queue
+	if it.i == len(node.items) {
+		it.nodes[0] = nil
+		it.nodes = append(it.nodes[1:], node.children...)
+		it.i = 0
+	}
+
+	return key, value
+}
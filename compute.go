@@ -0,0 +1,106 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import "reflect"
+
+// ComputeFunc call by Compute with old value of key, return new value of key, keep false to delete the key
+// fn is called with map lock held, so it must not call any method of the same map
+type ComputeFunc func(old interface{}, exists bool) (new interface{}, keep bool)
+
+// what compute do with the key after fn return
+type computeOp int
+
+const (
+	computePut    computeOp = iota // put the new value
+	computeDelete                  // delete the key, nothing happen when key not exist
+	computeKeep                    // leave the key as it is, no write, no event and no count
+)
+
+// fn call inside map, like ComputeFunc but can leave the key unchanged
+type computeOpFunc func(old interface{}, exists bool) (new interface{}, op computeOp)
+
+// compute of map, lock and find key once, return value of key after fn
+// lock free map may call fn again when other goroutine win, so fn should set all results every call
+type computeMethod func(key string, fn computeOpFunc) (value interface{}, exist bool)
+
+// keep false of ComputeFunc is delete, it never leave the key unchanged
+func (fn ComputeFunc) op() computeOpFunc {
+	return func(old interface{}, exists bool) (interface{}, computeOp) {
+		value, keep := fn(old, exists)
+		if !keep {
+			return nil, computeDelete
+		}
+
+		return value, computePut
+	}
+}
+
+// put value if key not exist, otherwise return the exist value
+func putIfAbsent(compute computeMethod, key string, value interface{}) (actual interface{}, loaded bool) {
+	compute(key, func(old interface{}, exists bool) (interface{}, computeOp) {
+		if exists {
+			actual, loaded = old, true
+			return nil, computeKeep
+		}
+
+		actual, loaded = value, false
+		return value, computePut
+	})
+
+	return
+}
+
+// put value only if key exist, return the old value
+func replace(compute computeMethod, key string, value interface{}) (old interface{}, replaced bool) {
+	compute(key, func(v interface{}, exists bool) (interface{}, computeOp) {
+		old, replaced = v, exists
+		if !exists {
+			return nil, computeKeep
+		}
+
+		return value, computePut
+	})
+
+	return
+}
+
+// put new only if value of key == old, value can not compare such as slice never equal
+func compareAndSwap(compute computeMethod, key string, old, new interface{}) (swapped bool) {
+	compute(key, func(v interface{}, exists bool) (interface{}, computeOp) {
+		swapped = exists && equalValue(v, old)
+		if !swapped {
+			return nil, computeKeep
+		}
+
+		return new, computePut
+	})
+
+	return
+}
+
+// delete key only if value of key == old, value can not compare such as slice never equal
+func compareAndDelete(compute computeMethod, key string, old interface{}) (deleted bool) {
+	compute(key, func(v interface{}, exists bool) (interface{}, computeOp) {
+		deleted = exists && equalValue(v, old)
+		if !deleted {
+			return nil, computeKeep
+		}
+
+		return nil, computeDelete
+	})
+
+	return
+}
+
+// a == b, false instead of panic when they are the same type which can not compare
+func equalValue(a, b interface{}) bool {
+	if t := reflect.TypeOf(a); t != nil && !t.Comparable() {
+		return false
+	}
+
+	return a == b
+}
@@ -0,0 +1,148 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+// cursor position
+const (
+	cursorBeforeFirst = iota // cursor before min key, Next will move to min key
+	cursorOnKey              // cursor on a key pairs
+	cursorAfterLast          // cursor after max key, Prev will move to max key
+)
+
+// Cursor bidirectional iterator, can seek and delete while walking
+// cursor only remember the key it stay on, every move find the neighbor key from tree again, cost O(logN)
+// so it is still valid after delete or put, but Value may be old when other goroutine put the same key
+type Cursor interface {
+	MapIterator                            // HasNext and Next, Next move to next key pairs and return it
+	HasPrev() bool                         // has previous key pairs
+	Prev() (key string, value interface{}) // move to previous key pairs and return it
+	Seek(key string) bool                  // move to the least key pairs greater than or equal to key
+	First() bool                           // move to min key pairs
+	Last() bool                            // move to max key pairs
+	Valid() bool                           // cursor stay on a key pairs
+	Key() string                           // key of cursor
+	Value() interface{}                    // value of cursor
+	Delete()                               // delete key pairs of cursor, cursor still stay here and can move
+}
+
+// cursor implement by nearest key lookup of map
+type mapCursor struct {
+	m     Map         // map of cursor
+	pos   int         // cursor position
+	key   string      // key of cursor
+	value interface{} // value of cursor
+}
+
+func newCursor(m Map) *mapCursor {
+	return &mapCursor{
+		m:   m,
+		pos: cursorBeforeFirst,
+	}
+}
+
+// move cursor to key pairs, if not exist, move to end
+func (c *mapCursor) moveTo(key string, value interface{}, exist bool, end int) bool {
+	if !exist {
+		c.pos = end
+		c.key = ""
+		c.value = nil
+		return false
+	}
+
+	c.pos = cursorOnKey
+	c.key = key
+	c.value = value
+	return true
+}
+
+// find next key pairs but not move
+func (c *mapCursor) next() (key string, value interface{}, exist bool) {
+	switch c.pos {
+	case cursorBeforeFirst:
+		return c.m.MinKey()
+	case cursorOnKey:
+		return c.m.Higher(c.key)
+	}
+
+	return
+}
+
+// find previous key pairs but not move
+func (c *mapCursor) prev() (key string, value interface{}, exist bool) {
+	switch c.pos {
+	case cursorAfterLast:
+		return c.m.MaxKey()
+	case cursorOnKey:
+		return c.m.Lower(c.key)
+	}
+
+	return
+}
+
+func (c *mapCursor) HasNext() bool {
+	_, _, exist := c.next()
+	return exist
+}
+
+// Next move to next key pairs, if not exist, cursor move to the end and return empty
+func (c *mapCursor) Next() (key string, value interface{}) {
+	key, value, exist := c.next()
+	c.moveTo(key, value, exist, cursorAfterLast)
+	return c.key, c.value
+}
+
+func (c *mapCursor) HasPrev() bool {
+	_, _, exist := c.prev()
+	return exist
+}
+
+// Prev move to previous key pairs, if not exist, cursor move to the begin and return empty
+func (c *mapCursor) Prev() (key string, value interface{}) {
+	key, value, exist := c.prev()
+	c.moveTo(key, value, exist, cursorBeforeFirst)
+	return c.key, c.value
+}
+
+// Seek if not exist, cursor move to the end
+func (c *mapCursor) Seek(key string) bool {
+	ceilingKey, value, exist := c.m.Ceiling(key)
+	return c.moveTo(ceilingKey, value, exist, cursorAfterLast)
+}
+
+// First if map is empty, cursor move to the end
+func (c *mapCursor) First() bool {
+	key, value, exist := c.m.MinKey()
+	return c.moveTo(key, value, exist, cursorAfterLast)
+}
+
+// Last if map is empty, cursor move to the begin
+func (c *mapCursor) Last() bool {
+	key, value, exist := c.m.MaxKey()
+	return c.moveTo(key, value, exist, cursorBeforeFirst)
+}
+
+func (c *mapCursor) Valid() bool {
+	return c.pos == cursorOnKey
+}
+
+func (c *mapCursor) Key() string {
+	return c.key
+}
+
+func (c *mapCursor) Value() interface{} {
+	return c.value
+}
+
+// Err cursor find key from tree every move, never fail
+func (c *mapCursor) Err() error {
+	return nil
+}
+
+func (c *mapCursor) Delete() {
+	if c.pos == cursorOnKey {
+		c.m.Delete(c.key)
+	}
+}
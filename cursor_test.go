@@ -0,0 +1,79 @@
+package gomap
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	for _, tm := range testMaps {
+		m := tm.new()
+		c := m.Cursor()
+		if c.HasNext() || c.HasPrev() || c.First() || c.Last() || c.Valid() {
+			t.Fatalf("%s empty map cursor can move", tm.name)
+		}
+
+		for i := 10; i < 100; i += 10 {
+			key := fmt.Sprintf("%d", i)
+			m.Put(key, key)
+		}
+
+		// walk like iterator
+		c = m.Cursor()
+		n := 0
+		for c.HasNext() {
+			k, v := c.Next()
+			if k != fmt.Sprintf("%d", 10+n*10) || v != k || c.Key() != k || c.Value() != v {
+				t.Fatalf("%s cursor next get %s", tm.name, k)
+			}
+			n++
+		}
+
+		if n != 9 {
+			t.Fatalf("%s cursor walk %d keys", tm.name, n)
+		}
+
+		// seek then move back and forth
+		if !c.Seek("35") || c.Key() != "40" {
+			t.Fatalf("%s cursor seek get %s", tm.name, c.Key())
+		}
+
+		if k, _ := c.Prev(); k != "30" {
+			t.Fatalf("%s cursor prev get %s", tm.name, k)
+		}
+
+		if k, _ := c.Next(); k != "40" {
+			t.Fatalf("%s cursor next get %s", tm.name, k)
+		}
+
+		// delete current and continue
+		c.Delete()
+		if m.Contains("40") || !c.Valid() || c.Key() != "40" {
+			t.Fatalf("%s cursor delete fail", tm.name)
+		}
+
+		if k, _ := c.Next(); k != "50" {
+			t.Fatalf("%s cursor next after delete get %s", tm.name, k)
+		}
+
+		if k, _ := c.Prev(); k != "30" {
+			t.Fatalf("%s cursor prev after delete get %s", tm.name, k)
+		}
+
+		// delete all from last to first
+		for ok := c.Last(); ok; {
+			c.Delete()
+			c.Prev()
+			ok = c.Valid()
+		}
+
+		if m.Len() != 0 {
+			t.Fatalf("%s cursor delete all remain %d", tm.name, m.Len())
+		}
+
+		// out of range
+		if c.Seek("99") || c.Valid() || c.HasNext() {
+			t.Fatalf("%s cursor seek out of range", tm.name)
+		}
+	}
+}
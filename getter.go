@@ -0,0 +1,94 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+// get of map, find value of key
+type getMethod func(key string) (value interface{}, exist bool)
+
+// get value of key as int, err when value is other type
+func getInt(get getMethod, key string) (value int, exist bool, err error) {
+	var v interface{}
+	v, exist = get(key)
+	if !exist {
+		return
+	}
+
+	value, ok := v.(int)
+	if !ok {
+		err = ReflectError(v)
+		return
+	}
+
+	return value, true, nil
+}
+
+// get value of key as int64, err when value is other type
+func getInt64(get getMethod, key string) (value int64, exist bool, err error) {
+	var v interface{}
+	v, exist = get(key)
+	if !exist {
+		return
+	}
+
+	value, ok := v.(int64)
+	if !ok {
+		err = ReflectError(v)
+		return
+	}
+
+	return value, true, nil
+}
+
+// get value of key as string, err when value is other type
+func getString(get getMethod, key string) (value string, exist bool, err error) {
+	var v interface{}
+	v, exist = get(key)
+	if !exist {
+		return
+	}
+
+	value, ok := v.(string)
+	if !ok {
+		err = ReflectError(v)
+		return
+	}
+
+	return value, true, nil
+}
+
+// get value of key as float64, err when value is other type
+func getFloat64(get getMethod, key string) (value float64, exist bool, err error) {
+	var v interface{}
+	v, exist = get(key)
+	if !exist {
+		return
+	}
+
+	value, ok := v.(float64)
+	if !ok {
+		err = ReflectError(v)
+		return
+	}
+
+	return value, true, nil
+}
+
+// get value of key as []byte, err when value is other type
+func getBytes(get getMethod, key string) (value []byte, exist bool, err error) {
+	var v interface{}
+	v, exist = get(key)
+	if !exist {
+		return
+	}
+
+	value, ok := v.([]byte)
+	if !ok {
+		err = ReflectError(v)
+		return
+	}
+
+	return value, true, nil
+}
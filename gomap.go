@@ -5,16 +5,23 @@
 */
 package gomap // import "github.com/hunterhug/gomap"
 
-import "strings"
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// ErrPrefixComparator custom comparator may not keep keys with same prefix together
+var ErrPrefixComparator = errors.New("prefix scan only support default comparator")
+
+// ErrConcurrentModification map add or delete key during iterate
+var ErrConcurrentModification = errors.New("map modified during iterate")
 
 type comparator func(key1, key2 string) int64
 
-// Map method
-// design to be concurrent safe
-// should support int key?
-type Map interface {
-	Put(key string, value interface{})                            // put key pairs
-	Delete(key string)                                            // delete a key
+// ReadOnlyMap read method of map, snapshot is a read only map without any write method
+type ReadOnlyMap interface {
 	Get(key string) (value interface{}, exist bool)               // get value from key
 	GetInt(key string) (value int, exist bool, err error)         // get value auto change to Int
 	GetInt64(key string) (value int64, exist bool, err error)     // get value auto change to Int64
@@ -28,16 +35,100 @@ type Map interface {
 	Iterator() MapIterator                                        // map iterator, iterator from top to bottom which is layer order
 	MaxKey() (key string, value interface{}, exist bool)          // find max key pairs
 	MinKey() (key string, value interface{}, exist bool)          // find min key pairs
-	SetComparator(comparator) Map                                 // set compare func to control key compare
 	Check() bool                                                  // just help
 	Height() int64                                                // just help
+	Stats() Stats                                                 // live counters, cheap and race free
+
+	// nearest key lookup, use the order of tree
+	Floor(key string) (floorKey string, value interface{}, exist bool)     // find the greatest key pairs less than or equal to key
+	Ceiling(key string) (ceilingKey string, value interface{}, exist bool) // find the least key pairs greater than or equal to key
+	Lower(key string) (lowerKey string, value interface{}, exist bool)     // find the greatest key pairs strictly less than key
+	Higher(key string) (higherKey string, value interface{}, exist bool)   // find the least key pairs strictly greater than key
+
+	// order statistic, use the size of sub tree
+	Rank(key string) int64                                      // num of keys strictly less than key
+	Select(i int64) (key string, value interface{}, exist bool) // find the i-th smallest key pairs, i start from 0
+
+	// sorted iterator
+	AscendIterator() MapIterator  // map iterator, iterator from min key to max key which is mid order
+	DescendIterator() MapIterator // map iterator, iterator from max key to min key which is reverse mid order
+	KeySortedListDesc() []string  // map key out to list sorted desc
+
+	// range iterator
+	Range(from, to string, opt RangeOption) MapIterator // map iterator, iterator key between from and to, sorted
+
+	// prefix scan, only support default comparator, otherwise return ErrPrefixComparator
+	PrefixIterator(prefix string) (MapIterator, error) // map iterator, iterator key with prefix, sorted
+	KeysWithPrefix(prefix string) ([]string, error)    // map key with prefix out to list sorted
+
+	// walk key pairs with map lock held, stop when fn return false
+	Ascend(fn WalkFunc)                                       // walk all key pairs from min to max
+	Descend(fn WalkFunc)                                      // walk all key pairs from max to min
+	AscendGreaterOrEqual(pivot string, fn WalkFunc)           // walk key pairs which pivot <= key, from min to max
+	AscendLessThan(pivot string, fn WalkFunc)                 // walk key pairs which key < pivot, from min to max
+	AscendRange(greaterOrEqual, lessThan string, fn WalkFunc) // walk key pairs which greaterOrEqual <= key < lessThan, from min to max
+}
+
+// Map method
+// design to be concurrent safe
+// should support int key?
+type Map interface {
+	ReadOnlyMap
+
+	Put(key string, value interface{}) // put key pairs
+	Delete(key string)                 // delete a key
+	SetComparator(comparator) Map      // set compare func to control key compare
+
+	// safe iterator for long running goroutine, never fail or crash under concurrent write
+	SafeIterator(mode IteratorMode) MapIterator // map iterator from min key to max key, IteratorSnapshot or IteratorWeak
+
+	// bidirectional cursor
+	Cursor() Cursor // cursor before min key, can move forward and back, seek key and delete key
+
+	// atomic delete many
+	DeleteRange(from, to string) int64                   // delete keys which from <= key <= to, return num of deleted keys
+	PopMin() (key string, value interface{}, exist bool) // find min key pairs and delete it
+	PopMax() (key string, value interface{}, exist bool) // find max key pairs and delete it
+
+	// point in time view, it has no write method, O(1) on path copying trees, O(N) on the others
+	Snapshot() ReadOnlyMap // read only view of map now, later write on map not change it
+
+	// atomic read modify write, find key once under lock
+	PutIfAbsent(key string, value interface{}) (actual interface{}, loaded bool) // put if key not exist, otherwise return the exist value and loaded true
+	Replace(key string, value interface{}) (old interface{}, replaced bool)      // put only if key exist, return the old value
+	CompareAndSwap(key string, old, new interface{}) (swapped bool)              // put new only if value of key == old, false if value can not compare
+	CompareAndDelete(key string, old interface{}) (deleted bool)                 // delete only if value of key == old, false if value can not compare
+	Compute(key string, fn ComputeFunc) (value interface{}, exist bool)          // put value return by fn, delete key if fn return keep false
+
+	// transaction, writes apply on commit under one lock
+	Begin() Txn // begin a transaction, other goroutines never see half writes of it
+
+	// watch change, event send inside write, channel closed after ctx done
+	Watch(ctx context.Context, key string, opt WatchOption) <-chan WatchEvent          // watch put and delete of key
+	WatchPrefix(ctx context.Context, prefix string, opt WatchOption) <-chan WatchEvent // watch put and delete of keys with prefix
+}
+
+// snapshot of backend hide behind it, so type assert to Map can not reach write method
+type readOnlyMap struct {
+	ReadOnlyMap
 }
 
 // MapIterator Iterator concurrent not safe
 // you should deal by yourself
+// if map add or delete key during iterate, HasNext return false and Err return ErrConcurrentModification
 type MapIterator interface {
 	HasNext() bool
 	Next() (key string, value interface{})
+	Err() error
+}
+
+// RangeOption control the bound of Range
+// zero value means from <= key <= to
+type RangeOption struct {
+	FromExclusive bool // from < key, not from <= key
+	ToExclusive   bool // key < to, not key <= to
+	FromUnbounded bool // ignore from, start at min key
+	ToUnbounded   bool // ignore to, end at max key
 }
 
 // NewMap default map is rbt implement
@@ -79,3 +170,8 @@ func NewAVLMap() Map {
 func comparatorDefault(key1, key2 string) int64 {
 	return int64(strings.Compare(key1, key2))
 }
+
+// is default comparator, which keep keys with same prefix together
+func isDefaultComparator(c comparator) bool {
+	return reflect.ValueOf(c).Pointer() == reflect.ValueOf(comparatorDefault).Pointer()
+}
@@ -6,8 +6,14 @@
 package gomap
 
 import (
+	"context"
+	"encoding/json"
+	"expvar"
 	"fmt"
 	"math/rand"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -164,3 +170,1482 @@ func TestNew(t *testing.T) {
 		fmt.Println("is a rb tree,len:", m.Len())
 	}
 }
+
+// lock free skip list, many goroutines put and delete while others read and iterate
+func TestSkipListMap_Concurrent(t *testing.T) {
+	m := NewSkipListMap()
+	workers := 8
+	num := 2000
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+
+	// readers, iterator must keep sorted and never fail
+	for g := 0; g < 2; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				last := ""
+				it := m.AscendIterator()
+				for it.HasNext() {
+					k, _ := it.Next()
+					if last != "" && k <= last {
+						t.Errorf("iterator not sorted %s after %s", k, last)
+						return
+					}
+					last = k
+				}
+				m.Floor("5")
+				m.MaxKey()
+			}
+		}()
+	}
+
+	// writers, every worker own keys with its prefix, and all fight for shared keys
+	var writers sync.WaitGroup
+	result := make([]map[string]interface{}, workers)
+	for g := 0; g < workers; g++ {
+		writers.Add(1)
+		go func(g int) {
+			defer writers.Done()
+			rw := make(map[string]interface{})
+			r := rand.New(rand.NewSource(int64(g)))
+			for i := 0; i < num; i++ {
+				key := fmt.Sprintf("%d_%d", g, r.Intn(num/2))
+				if r.Intn(3) == 0 {
+					m.Delete(key)
+					delete(rw, key)
+				} else {
+					m.Put(key, i)
+					rw[key] = i
+				}
+
+				m.Compute("shared", func(old interface{}, exists bool) (interface{}, bool) {
+					if !exists {
+						return 1, true
+					}
+					return old.(int) + 1, true
+				})
+			}
+			result[g] = rw
+		}(g)
+	}
+
+	writers.Wait()
+	close(stop)
+	wg.Wait()
+
+	total := 1
+	for _, rw := range result {
+		total += len(rw)
+		for k, v := range rw {
+			if vv, ok := m.Get(k); !ok || vv != v {
+				t.Fatalf("key %s get %v want %v", k, vv, v)
+			}
+		}
+	}
+
+	if v, _ := m.Get("shared"); v != workers*num {
+		t.Fatalf("shared get %v want %d", v, workers*num)
+	}
+
+	if m.Len() != int64(total) || !m.Check() {
+		t.Fatalf("len %d want %d", m.Len(), total)
+	}
+}
+
+// every backend should pass the same case
+var testMaps = []struct {
+	name string
+	new  func() Map
+}{
+	{"rbt", NewRBMap},
+	{"llrb", NewLLRBMap},
+	{"avl", NewAVLMap},
+	{"avl recursion", NewAVLRecursionMap},
+	{"sharded", func() Map { return NewShardedMap(4, ShardOption{}) }},
+	{"skiplist", NewSkipListMap},
+	{"btree", func() Map { return NewBTreeMap(4) }},
+	{"treap", func() Map { return NewTreapMap(int64(randNum)) }},
+	{"splay", NewSplayMap},
+	{"scapegoat", func() Map { return NewScapegoatMap(0.7) }},
+	{"wbt", NewWBTMap},
+}
+
+func TestMap_FloorCeiling(t *testing.T) {
+	for _, tm := range testMaps {
+		m := tm.new()
+		for _, key := range []string{"b", "d", "f"} {
+			m.Put(key, key+"_v")
+		}
+
+		cases := []struct {
+			key                           string
+			floor, ceiling, lower, higher string
+		}{
+			{"a", "", "b", "", "b"},
+			{"b", "b", "b", "", "d"},
+			{"c", "b", "d", "b", "d"},
+			{"d", "d", "d", "b", "f"},
+			{"f", "f", "f", "d", ""},
+			{"g", "f", "", "f", ""},
+		}
+
+		for _, c := range cases {
+			check := func(op string, want string, k string, v interface{}, exist bool) {
+				if exist != (want != "") || k != want || (exist && v != want+"_v") {
+					t.Fatalf("%s %s(%q) = %q,%v,%v want %q", tm.name, op, c.key, k, v, exist, want)
+				}
+			}
+
+			k, v, exist := m.Floor(c.key)
+			check("Floor", c.floor, k, v, exist)
+			k, v, exist = m.Ceiling(c.key)
+			check("Ceiling", c.ceiling, k, v, exist)
+			k, v, exist = m.Lower(c.key)
+			check("Lower", c.lower, k, v, exist)
+			k, v, exist = m.Higher(c.key)
+			check("Higher", c.higher, k, v, exist)
+		}
+	}
+}
+
+func TestMap_RankSelect(t *testing.T) {
+	for _, tm := range testMaps {
+		m := tm.new()
+		rw := make(map[string]struct{})
+		r := rand.New(rand.NewSource(int64(randNum)))
+		for i := 0; i < 2000; i++ {
+			key := fmt.Sprintf("%d", r.Int63n(1000))
+			if r.Intn(3) == 0 {
+				m.Delete(key)
+				delete(rw, key)
+			} else {
+				m.Put(key, key)
+				rw[key] = struct{}{}
+			}
+		}
+
+		if !m.Check() {
+			t.Fatalf("%s is not a valid tree", tm.name)
+		}
+
+		keys := m.KeySortedList()
+		if int64(len(keys)) != m.Len() || len(keys) != len(rw) {
+			t.Fatalf("%s len %d != %d", tm.name, len(keys), len(rw))
+		}
+
+		for i, key := range keys {
+			if rank := m.Rank(key); rank != int64(i) {
+				t.Fatalf("%s Rank(%s) = %d want %d", tm.name, key, rank, i)
+			}
+
+			k, v, exist := m.Select(int64(i))
+			if !exist || k != key || v != key {
+				t.Fatalf("%s Select(%d) = %s,%v want %s", tm.name, i, k, exist, key)
+			}
+		}
+
+		if _, _, exist := m.Select(m.Len()); exist {
+			t.Fatalf("%s Select out of range", tm.name)
+		}
+
+		if _, _, exist := m.Select(-1); exist {
+			t.Fatalf("%s Select out of range", tm.name)
+		}
+
+		if rank := m.Rank(""); rank != 0 {
+			t.Fatalf("%s Rank of min = %d", tm.name, rank)
+		}
+
+		if rank := m.Rank("a"); rank != m.Len() {
+			t.Fatalf("%s Rank after max = %d", tm.name, rank)
+		}
+	}
+}
+
+func TestMap_AscendIterator(t *testing.T) {
+	for _, tm := range testMaps {
+		m := tm.new()
+		if m.AscendIterator().HasNext() {
+			t.Fatalf("%s empty map has next", tm.name)
+		}
+
+		r := rand.New(rand.NewSource(int64(randNum)))
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("%d", r.Int63n(10000))
+			m.Put(key, key+"_v")
+		}
+
+		keys := m.KeySortedList()
+		iterator := m.AscendIterator()
+		for _, key := range keys {
+			if !iterator.HasNext() {
+				t.Fatalf("%s iterator stop early", tm.name)
+			}
+
+			k, v := iterator.Next()
+			if k != key || v != key+"_v" {
+				t.Fatalf("%s iterator get %s:%v want %s", tm.name, k, v, key)
+			}
+		}
+
+		if iterator.HasNext() {
+			t.Fatalf("%s iterator has more keys", tm.name)
+		}
+	}
+}
+
+func TestMap_DescendIterator(t *testing.T) {
+	for _, tm := range testMaps {
+		m := tm.new()
+		if m.DescendIterator().HasNext() || len(m.KeySortedListDesc()) != 0 {
+			t.Fatalf("%s empty map has next", tm.name)
+		}
+
+		r := rand.New(rand.NewSource(int64(randNum)))
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("%d", r.Int63n(10000))
+			m.Put(key, key+"_v")
+		}
+
+		keys := m.KeySortedList()
+		descKeys := m.KeySortedListDesc()
+		if len(descKeys) != len(keys) {
+			t.Fatalf("%s desc key list len %d want %d", tm.name, len(descKeys), len(keys))
+		}
+
+		iterator := m.DescendIterator()
+		for i := len(keys) - 1; i >= 0; i-- {
+			if descKeys[len(keys)-1-i] != keys[i] {
+				t.Fatalf("%s desc key list get %s want %s", tm.name, descKeys[len(keys)-1-i], keys[i])
+			}
+
+			k, v := iterator.Next()
+			if k != keys[i] || v != keys[i]+"_v" {
+				t.Fatalf("%s iterator get %s:%v want %s", tm.name, k, v, keys[i])
+			}
+		}
+
+		if iterator.HasNext() {
+			t.Fatalf("%s iterator has more keys", tm.name)
+		}
+	}
+}
+
+func TestMap_Range(t *testing.T) {
+	for _, tm := range testMaps {
+		m := tm.new()
+		if m.Range("a", "z", RangeOption{}).HasNext() {
+			t.Fatalf("%s empty map has next", tm.name)
+		}
+
+		for i := 10; i < 100; i += 10 {
+			key := fmt.Sprintf("%d", i)
+			m.Put(key, key)
+		}
+
+		cases := []struct {
+			from, to string
+			opt      RangeOption
+			want     string
+		}{
+			{"20", "50", RangeOption{}, "20,30,40,50"},
+			{"20", "50", RangeOption{FromExclusive: true}, "30,40,50"},
+			{"20", "50", RangeOption{ToExclusive: true}, "20,30,40"},
+			{"20", "50", RangeOption{FromExclusive: true, ToExclusive: true}, "30,40"},
+			{"15", "55", RangeOption{}, "20,30,40,50"},
+			{"", "35", RangeOption{FromUnbounded: true}, "10,20,30"},
+			{"75", "", RangeOption{ToUnbounded: true}, "80,90"},
+			{"", "", RangeOption{FromUnbounded: true, ToUnbounded: true}, "10,20,30,40,50,60,70,80,90"},
+			{"50", "20", RangeOption{}, ""},
+			{"91", "99", RangeOption{}, ""},
+			{"30", "30", RangeOption{}, "30"},
+			{"30", "30", RangeOption{ToExclusive: true}, ""},
+		}
+
+		for _, c := range cases {
+			got := ""
+			iterator := m.Range(c.from, c.to, c.opt)
+			for iterator.HasNext() {
+				k, _ := iterator.Next()
+				if got != "" {
+					got += ","
+				}
+				got += k
+			}
+
+			if got != c.want {
+				t.Fatalf("%s Range(%q,%q,%+v) = %s want %s", tm.name, c.from, c.to, c.opt, got, c.want)
+			}
+		}
+	}
+}
+
+func TestMap_Prefix(t *testing.T) {
+	for _, tm := range testMaps {
+		m := tm.new()
+		if keys, err := m.KeysWithPrefix("svc/"); err != nil || len(keys) != 0 {
+			t.Fatalf("%s empty map prefix get %v,%v", tm.name, keys, err)
+		}
+
+		for _, key := range []string{"svc", "svc/a/timeout", "svc/a/retry", "svc/b/timeout", "svca", "sv", "web/a"} {
+			m.Put(key, key)
+		}
+
+		cases := []struct {
+			prefix string
+			want   string
+		}{
+			{"svc/a/", "svc/a/retry,svc/a/timeout"},
+			{"svc/", "svc/a/retry,svc/a/timeout,svc/b/timeout"},
+			{"svc", "svc,svc/a/retry,svc/a/timeout,svc/b/timeout,svca"},
+			{"x", ""},
+			{"", "sv,svc,svc/a/retry,svc/a/timeout,svc/b/timeout,svca,web/a"},
+		}
+
+		for _, c := range cases {
+			keys, err := m.KeysWithPrefix(c.prefix)
+			if err != nil || strings.Join(keys, ",") != c.want {
+				t.Fatalf("%s KeysWithPrefix(%q) = %v,%v want %s", tm.name, c.prefix, keys, err, c.want)
+			}
+
+			iterator, err := m.PrefixIterator(c.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			keys = keys[:0]
+			for iterator.HasNext() {
+				k, _ := iterator.Next()
+				keys = append(keys, k)
+			}
+
+			if strings.Join(keys, ",") != c.want {
+				t.Fatalf("%s PrefixIterator(%q) = %v want %s", tm.name, c.prefix, keys, c.want)
+			}
+		}
+
+		// custom comparator can not prefix scan
+		m = tm.new().SetComparator(func(key1, key2 string) int64 {
+			return int64(len(key1) - len(key2))
+		})
+		if _, err := m.KeysWithPrefix("svc"); err != ErrPrefixComparator {
+			t.Fatalf("%s custom comparator prefix err: %v", tm.name, err)
+		}
+
+		if _, err := m.PrefixIterator("svc"); err != ErrPrefixComparator {
+			t.Fatalf("%s custom comparator prefix err: %v", tm.name, err)
+		}
+	}
+}
+
+func TestMap_DeleteRangePop(t *testing.T) {
+	for _, tm := range testMaps {
+		m := tm.new()
+		if _, _, exist := m.PopMin(); exist {
+			t.Fatalf("%s empty map pop", tm.name)
+		}
+
+		if n := m.DeleteRange("a", "z"); n != 0 {
+			t.Fatalf("%s empty map delete range %d", tm.name, n)
+		}
+
+		for i := 10; i < 100; i++ {
+			key := fmt.Sprintf("%d", i)
+			m.Put(key, key)
+		}
+
+		if n := m.DeleteRange("20", "39"); n != 20 || m.Len() != 70 || m.Contains("20") || m.Contains("39") || !m.Contains("40") {
+			t.Fatalf("%s delete range %d, len %d", tm.name, n, m.Len())
+		}
+
+		if !m.Check() {
+			t.Fatalf("%s is not a valid tree", tm.name)
+		}
+
+		if k, v, exist := m.PopMin(); !exist || k != "10" || v != "10" || m.Contains("10") {
+			t.Fatalf("%s pop min get %s", tm.name, k)
+		}
+
+		if k, v, exist := m.PopMax(); !exist || k != "99" || v != "99" || m.Contains("99") {
+			t.Fatalf("%s pop max get %s", tm.name, k)
+		}
+
+		// every key pop only once by many goroutine
+		var lock sync.Mutex
+		popped := make(map[string]int)
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					k, _, exist := m.PopMin()
+					if !exist {
+						return
+					}
+
+					lock.Lock()
+					popped[k]++
+					lock.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if len(popped) != 68 || m.Len() != 0 {
+			t.Fatalf("%s pop %d keys, remain %d", tm.name, len(popped), m.Len())
+		}
+
+		for k, n := range popped {
+			if n != 1 {
+				t.Fatalf("%s pop %s %d times", tm.name, k, n)
+			}
+		}
+	}
+}
+
+func TestMap_IteratorFailFast(t *testing.T) {
+	for _, tm := range testMaps {
+		// iterator of skip list is weakly consistent, never fail
+		if tm.name == "skiplist" {
+			continue
+		}
+
+		m := tm.new()
+		for i := 10; i < 100; i++ {
+			key := fmt.Sprintf("%d", i)
+			m.Put(key, key)
+		}
+
+		iterators := map[string]func() MapIterator{
+			"Iterator":        m.Iterator,
+			"AscendIterator":  m.AscendIterator,
+			"DescendIterator": m.DescendIterator,
+			"Range": func() MapIterator {
+				return m.Range("20", "80", RangeOption{})
+			},
+			"PrefixIterator": func() MapIterator {
+				it, _ := m.PrefixIterator("")
+				return it
+			},
+		}
+
+		for name, newIterator := range iterators {
+			// update value is not modification
+			iterator := newIterator()
+			iterator.Next()
+			m.Put("50", "new")
+			n := 1
+			for iterator.HasNext() {
+				iterator.Next()
+				n++
+			}
+
+			if iterator.Err() != nil || n < 2 {
+				t.Fatalf("%s %s err %v after %d keys", tm.name, name, iterator.Err(), n)
+			}
+
+			// add key during iterate
+			iterator = newIterator()
+			iterator.Next()
+			m.Put("5", "5")
+			if iterator.HasNext() || iterator.Err() != ErrConcurrentModification {
+				t.Fatalf("%s %s not fail fast after put", tm.name, name)
+			}
+
+			if k, v := iterator.Next(); k != "" || v != nil {
+				t.Fatalf("%s %s next after fail get %s", tm.name, name, k)
+			}
+
+			// delete key during iterate
+			iterator = newIterator()
+			m.Delete("5")
+			if iterator.HasNext() || iterator.Err() != ErrConcurrentModification {
+				t.Fatalf("%s %s not fail fast after delete", tm.name, name)
+			}
+
+			// delete not exist key is not modification
+			iterator = newIterator()
+			m.Delete("5")
+			if !iterator.HasNext() || iterator.Err() != nil {
+				t.Fatalf("%s %s fail after delete nothing", tm.name, name)
+			}
+		}
+	}
+}
+
+func TestMap_Walk(t *testing.T) {
+	for _, tm := range testMaps {
+		m := tm.new()
+		m.Ascend(func(key string, value interface{}) bool {
+			t.Fatalf("%s empty map walk %s", tm.name, key)
+			return true
+		})
+
+		for i := 10; i < 100; i += 10 {
+			key := fmt.Sprintf("%d", i)
+			m.Put(key, key+"_v")
+		}
+
+		// walk collect keys, stop after limit keys
+		walk := func(limit int, f func(fn WalkFunc)) string {
+			keys := make([]string, 0)
+			f(func(key string, value interface{}) bool {
+				if value != key+"_v" {
+					t.Fatalf("%s walk %s get value %v", tm.name, key, value)
+				}
+				keys = append(keys, key)
+				return len(keys) < limit
+			})
+			return strings.Join(keys, ",")
+		}
+
+		cases := []struct {
+			name string
+			got  string
+			want string
+		}{
+			{"Ascend", walk(100, m.Ascend), "10,20,30,40,50,60,70,80,90"},
+			{"Ascend stop", walk(3, m.Ascend), "10,20,30"},
+			{"Descend", walk(100, m.Descend), "90,80,70,60,50,40,30,20,10"},
+			{"Descend stop", walk(2, m.Descend), "90,80"},
+			{"AscendGreaterOrEqual", walk(100, func(fn WalkFunc) { m.AscendGreaterOrEqual("70", fn) }), "70,80,90"},
+			{"AscendGreaterOrEqual stop", walk(1, func(fn WalkFunc) { m.AscendGreaterOrEqual("65", fn) }), "70"},
+			{"AscendLessThan", walk(100, func(fn WalkFunc) { m.AscendLessThan("30", fn) }), "10,20"},
+			{"AscendRange", walk(100, func(fn WalkFunc) { m.AscendRange("30", "60", fn) }), "30,40,50"},
+			{"AscendRange stop", walk(2, func(fn WalkFunc) { m.AscendRange("25", "60", fn) }), "30,40"},
+			{"AscendRange empty", walk(100, func(fn WalkFunc) { m.AscendRange("60", "30", fn) }), ""},
+		}
+
+		for _, c := range cases {
+			if c.got != c.want {
+				t.Fatalf("%s %s get %s want %s", tm.name, c.name, c.got, c.want)
+			}
+		}
+
+		// walk should not alloc
+		n := 0
+		fn := func(key string, value interface{}) bool {
+			n++
+			return true
+		}
+		// sharded map merge shards with heap, alloc is expected
+		if allocs := testing.AllocsPerRun(10, func() { m.AscendRange("20", "80", fn) }); allocs > 0 && tm.name != "sharded" {
+			t.Fatalf("%s AscendRange alloc %v", tm.name, allocs)
+		}
+	}
+}
+
+func TestMap_Snapshot(t *testing.T) {
+	for _, tm := range testMaps {
+		m := tm.new()
+		r := rand.New(rand.NewSource(int64(randNum)))
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("%d", r.Int63n(2000))
+			m.Put(key, key)
+		}
+
+		// snapshot keep key pairs at that time
+		type version struct {
+			snap ReadOnlyMap
+			rw   map[string]interface{}
+		}
+		versions := make([]version, 0)
+		save := func() {
+			rw := make(map[string]interface{})
+			m.Ascend(func(key string, value interface{}) bool {
+				rw[key] = value
+				return true
+			})
+			versions = append(versions, version{snap: m.Snapshot(), rw: rw})
+		}
+
+		save()
+
+		// reader iterate snapshot while writer keep going
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func(snap ReadOnlyMap) {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				it := snap.AscendIterator()
+				for it.HasNext() {
+					it.Next()
+				}
+
+				if it.Err() != nil {
+					t.Errorf("%s snapshot iterator err %v", tm.name, it.Err())
+					return
+				}
+			}
+		}(versions[0].snap)
+
+		for i := 0; i < 3000; i++ {
+			key := fmt.Sprintf("%d", r.Int63n(2000))
+			switch r.Intn(3) {
+			case 0:
+				m.Delete(key)
+			case 1:
+				m.Put(key, key+"_new")
+			default:
+				m.Put(key, i)
+			}
+
+			if i%1000 == 999 {
+				save()
+			}
+		}
+
+		wg.Wait()
+
+		if !m.Check() {
+			t.Fatalf("%s map check fail after write", tm.name)
+		}
+
+		for i, v := range versions {
+			if v.snap.Len() != int64(len(v.rw)) || !v.snap.Check() {
+				t.Fatalf("%s snapshot %d len %d want %d", tm.name, i, v.snap.Len(), len(v.rw))
+			}
+
+			n := 0
+			v.snap.Ascend(func(key string, value interface{}) bool {
+				if want, ok := v.rw[key]; !ok || want != value {
+					t.Fatalf("%s snapshot %d key %s get %v want %v", tm.name, i, key, value, want)
+				}
+				n++
+				return true
+			})
+
+			if n != len(v.rw) {
+				t.Fatalf("%s snapshot %d walk %d keys want %d", tm.name, i, n, len(v.rw))
+			}
+		}
+
+		// snapshot has no write method, even behind type assert
+		if _, ok := versions[0].snap.(Map); ok {
+			t.Fatalf("%s snapshot is a writable map", tm.name)
+		}
+	}
+}
+
+func TestMap_Txn(t *testing.T) {
+	for _, tm := range testMaps {
+		m := tm.new()
+		m.Put("a", 1000)
+		m.Put("b", 0)
+		m.Put("c", "c")
+
+		// read own writes, map not change before commit
+		txn := m.Begin()
+		txn.Put("d", "d")
+		txn.Delete("c")
+		txn.Put("a", 900)
+		if v, ok := txn.Get("d"); !ok || v != "d" {
+			t.Fatalf("%s txn get own put %v %v", tm.name, v, ok)
+		}
+		if _, ok := txn.Get("c"); ok {
+			t.Fatalf("%s txn get own delete", tm.name)
+		}
+		if v, ok := txn.Get("b"); !ok || v != 0 {
+			t.Fatalf("%s txn get from map %v %v", tm.name, v, ok)
+		}
+		if m.Contains("d") || !m.Contains("c") {
+			t.Fatalf("%s map change before commit", tm.name)
+		}
+
+		if err := txn.Commit(); err != nil {
+			t.Fatalf("%s commit err %v", tm.name, err)
+		}
+		if v, _ := m.Get("a"); v != 900 || !m.Contains("d") || m.Contains("c") || m.Len() != 3 || !m.Check() {
+			t.Fatalf("%s map after commit %v", tm.name, m.KeySortedList())
+		}
+		if err := txn.Commit(); err != ErrTxnDone {
+			t.Fatalf("%s commit twice err %v", tm.name, err)
+		}
+
+		// rollback drop writes
+		txn = m.Begin()
+		txn.Put("e", "e")
+		txn.Rollback()
+		if m.Contains("e") || txn.Commit() != ErrTxnDone {
+			t.Fatalf("%s rollback write map", tm.name)
+		}
+
+		// move balance between two keys, reader never see half of it
+		m.Put("a", 1000)
+		m.Put("b", 0)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				txn := m.Begin()
+				a, _ := txn.Get("a")
+				b, _ := txn.Get("b")
+				txn.Put("a", a.(int)-1)
+				txn.Put("b", b.(int)+1)
+				if err := txn.Commit(); err != nil {
+					t.Errorf("%s commit err %v", tm.name, err)
+					return
+				}
+			}
+		}()
+
+		for i := 0; i < 500; i++ {
+			snap := m.Snapshot()
+			a, _ := snap.Get("a")
+			b, _ := snap.Get("b")
+			if a.(int)+b.(int) != 1000 {
+				t.Fatalf("%s see half commit a %v b %v", tm.name, a, b)
+			}
+		}
+
+		wg.Wait()
+		if v, _ := m.Get("b"); v != 500 {
+			t.Fatalf("%s b get %v", tm.name, v)
+		}
+	}
+}
+
+func TestMap_TxnConflict(t *testing.T) {
+	for _, tm := range testMaps {
+		m := tm.new()
+		m.Put("a", 1)
+		m.Put("bytes", []byte("v"))
+
+		// key read changed by other writer, commit write nothing
+		txn := m.Begin()
+		a, _ := txn.Get("a")
+		txn.Get("x")
+		txn.Get("bytes")
+		txn.Put("a", a.(int)+1)
+		txn.Put("y", "y")
+		m.Put("a", 10)
+		if v, _ := txn.Get("a"); v != 2 {
+			t.Fatalf("%s txn get own write %v", tm.name, v)
+		}
+		if err := txn.Commit(); err != ErrTxnConflict {
+			t.Fatalf("%s commit after key changed err %v", tm.name, err)
+		}
+		if v, _ := m.Get("a"); v != 10 || m.Contains("y") || txn.Commit() != ErrTxnDone {
+			t.Fatalf("%s conflict txn write map", tm.name)
+		}
+
+		// key not exist when read, put by other writer
+		txn = m.Begin()
+		txn.Get("x")
+		txn.Put("y", "y")
+		m.Put("x", "x")
+		if err := txn.Commit(); err != ErrTxnConflict || m.Contains("y") {
+			t.Fatalf("%s commit after key added err %v", tm.name, err)
+		}
+
+		// value can not compare is the same when not changed
+		txn = m.Begin()
+		txn.Get("bytes")
+		txn.Put("y", "y")
+		if err := txn.Commit(); err != nil || !m.Contains("y") {
+			t.Fatalf("%s commit read bytes err %v", tm.name, err)
+		}
+
+		// many writers read and write the same keys, retry on conflict, no update is lost
+		workers, num := 4, 300
+		m.Put("a", workers*num)
+		m.Put("b", 0)
+		var wg sync.WaitGroup
+		for g := 0; g < workers; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < num; {
+					txn := m.Begin()
+					a, _ := txn.Get("a")
+					b, _ := txn.Get("b")
+					runtime.Gosched()
+					txn.Put("a", a.(int)-1)
+					txn.Put("b", b.(int)+1)
+					if err := txn.Commit(); err == nil {
+						i++
+					} else if err != ErrTxnConflict {
+						t.Errorf("%s commit err %v", tm.name, err)
+						return
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+		a, _ = m.Get("a")
+		if b, _ := m.Get("b"); a != 0 || b != workers*num {
+			t.Fatalf("%s lost update, a %v b %v", tm.name, a, b)
+		}
+	}
+}
+
+func TestMap_Compute(t *testing.T) {
+	for _, tm := range testMaps {
+		m := tm.new()
+
+		if actual, loaded := m.PutIfAbsent("a", 1); loaded || actual != 1 {
+			t.Fatalf("%s PutIfAbsent new get %v %v", tm.name, actual, loaded)
+		}
+		if actual, loaded := m.PutIfAbsent("a", 2); !loaded || actual != 1 {
+			t.Fatalf("%s PutIfAbsent exist get %v %v", tm.name, actual, loaded)
+		}
+		if old, replaced := m.Replace("b", 1); replaced || old != nil || m.Contains("b") {
+			t.Fatalf("%s Replace not exist get %v %v", tm.name, old, replaced)
+		}
+		if old, replaced := m.Replace("a", 3); !replaced || old != 1 {
+			t.Fatalf("%s Replace exist get %v %v", tm.name, old, replaced)
+		}
+		if m.CompareAndSwap("a", 1, 4) || m.CompareAndSwap("b", nil, 4) || !m.CompareAndSwap("a", 3, 4) {
+			t.Fatalf("%s CompareAndSwap wrong", tm.name)
+		}
+		if m.CompareAndDelete("a", 3) || m.CompareAndDelete("b", nil) || !m.CompareAndDelete("a", 4) || m.Contains("a") {
+			t.Fatalf("%s CompareAndDelete wrong", tm.name)
+		}
+
+		// value can not compare never equal, no panic
+		m.Put("s", []byte("s"))
+		if m.CompareAndSwap("s", []byte("s"), 1) || m.CompareAndDelete("s", []byte("s")) || !m.Contains("s") {
+			t.Fatalf("%s conditional op on slice success", tm.name)
+		}
+		m.Delete("s")
+
+		// failed conditional op write nothing, no event and no count
+		m.Put("a", 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := m.WatchPrefix(ctx, "", WatchOption{})
+		before := m.Stats()
+		if _, loaded := m.PutIfAbsent("a", 2); !loaded {
+			t.Fatalf("%s PutIfAbsent exist not loaded", tm.name)
+		}
+		if _, replaced := m.Replace("b", 2); replaced {
+			t.Fatalf("%s Replace not exist replaced", tm.name)
+		}
+		if m.CompareAndSwap("a", 2, 3) || m.CompareAndSwap("b", nil, 3) || m.CompareAndDelete("a", 2) || m.CompareAndDelete("b", nil) {
+			t.Fatalf("%s conditional op on wrong value success", tm.name)
+		}
+		if after := m.Stats(); after.Puts != before.Puts || after.Deletes != before.Deletes || after.Len != 1 {
+			t.Fatalf("%s failed conditional op count puts %d deletes %d, want %d %d", tm.name, after.Puts, after.Deletes, before.Puts, before.Deletes)
+		}
+		m.Put("c", 1)
+		if ev := recvEvent(t, tm.name, ch); ev.Key != "c" {
+			t.Fatalf("%s failed conditional op send event %+v", tm.name, ev)
+		}
+		cancel()
+		m.Delete("a")
+		m.Delete("c")
+
+		// compare with builtin map
+		rw := make(map[string]int)
+		r := rand.New(rand.NewSource(int64(randNum)))
+		for i := 0; i < 3000; i++ {
+			key := fmt.Sprintf("%d", r.Int63n(500))
+			n := r.Intn(4)
+			value, exist := m.Compute(key, func(old interface{}, exists bool) (interface{}, bool) {
+				if want, ok := rw[key]; ok != exists || (ok && want != old) {
+					t.Fatalf("%s Compute %s get old %v %v", tm.name, key, old, exists)
+				}
+
+				if n == 0 {
+					return nil, false
+				}
+
+				if !exists {
+					return n, true
+				}
+
+				return old.(int) + n, true
+			})
+
+			if n == 0 {
+				delete(rw, key)
+			} else {
+				rw[key] += n
+			}
+
+			if want, ok := rw[key]; ok != exist || (ok && want != value) {
+				t.Fatalf("%s Compute %s return %v %v", tm.name, key, value, exist)
+			}
+		}
+
+		if m.Len() != int64(len(rw)) || !m.Check() {
+			t.Fatalf("%s len %d want %d", tm.name, m.Len(), len(rw))
+		}
+
+		// concurrent add is atomic
+		m.Put("counter", 0)
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					m.Compute("counter", func(old interface{}, exists bool) (interface{}, bool) {
+						return old.(int) + 1, true
+					})
+
+					for {
+						v, _ := m.Get("counter")
+						if m.CompareAndSwap("counter", v, v.(int)+1) {
+							break
+						}
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		if v, _ := m.Get("counter"); v != 800 {
+			t.Fatalf("%s counter get %v", tm.name, v)
+		}
+	}
+}
+
+// receive event or fail after a while
+func recvEvent(t *testing.T, name string, ch <-chan WatchEvent) WatchEvent {
+	select {
+	case ev, ok := <-ch:
+		if !ok {
+			t.Fatalf("%s watch channel closed", name)
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s watch no event", name)
+	}
+
+	return WatchEvent{}
+}
+
+func TestMap_Watch(t *testing.T) {
+	for _, tm := range testMaps {
+		m := tm.new()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		keyCh := m.Watch(ctx, "a", WatchOption{})
+		prefixCh := m.WatchPrefix(ctx, "b", WatchOption{})
+
+		m.Put("a", 1)
+		m.Put("a", 2)
+		m.Delete("a")
+		m.Delete("a")
+		m.Put("b1", 1)
+		m.Compute("b2", func(old interface{}, exists bool) (interface{}, bool) {
+			return 2, true
+		})
+		m.PopMax()
+		m.Put("c", 1)
+
+		want := []WatchEvent{
+			{Type: EventPut, Key: "a", Value: 1},
+			{Type: EventPut, Key: "a", Value: 2, OldValue: 1, OldExist: true},
+			{Type: EventDelete, Key: "a", OldValue: 2, OldExist: true},
+		}
+		for _, w := range want {
+			if ev := recvEvent(t, tm.name, keyCh); ev != w {
+				t.Fatalf("%s Watch get %+v, want %+v", tm.name, ev, w)
+			}
+		}
+
+		want = []WatchEvent{
+			{Type: EventPut, Key: "b1", Value: 1},
+			{Type: EventPut, Key: "b2", Value: 2},
+			{Type: EventDelete, Key: "b2", OldValue: 2, OldExist: true},
+		}
+		for _, w := range want {
+			if ev := recvEvent(t, tm.name, prefixCh); ev != w {
+				t.Fatalf("%s WatchPrefix get %+v, want %+v", tm.name, ev, w)
+			}
+		}
+
+		// buffer full, drop and count it in next event
+		dropCh := m.Watch(ctx, "d", WatchOption{Buffer: 1, Overflow: OverflowDrop})
+		for i := 0; i < 3; i++ {
+			m.Put("d", i)
+		}
+		if ev := recvEvent(t, tm.name, dropCh); ev.Value != 0 || ev.Dropped != 0 {
+			t.Fatalf("%s drop get %+v", tm.name, ev)
+		}
+		m.Put("d", 3)
+		if ev := recvEvent(t, tm.name, dropCh); ev.Value != 3 || ev.Dropped != 2 {
+			t.Fatalf("%s drop get %+v", tm.name, ev)
+		}
+
+		// buffer full, writer wait for watcher
+		blockCh := m.Watch(ctx, "e", WatchOption{Buffer: 1, Overflow: OverflowBlock})
+		go func() {
+			for i := 0; i < 100; i++ {
+				m.Put("e", i)
+			}
+		}()
+		for i := 0; i < 100; i++ {
+			if ev := recvEvent(t, tm.name, blockCh); ev.Value != i {
+				t.Fatalf("%s block get %+v, want %d", tm.name, ev, i)
+			}
+		}
+
+		// all channels closed after ctx done, writer not blocked any more
+		stuckCh := m.Watch(ctx, "f", WatchOption{Buffer: 1, Overflow: OverflowBlock})
+		m.Put("f", 1)
+		done := make(chan struct{})
+		go func() {
+			m.Put("f", 2)
+			close(done)
+		}()
+
+		cancel()
+		<-done
+		for _, ch := range []<-chan WatchEvent{keyCh, prefixCh, dropCh, blockCh, stuckCh} {
+			for range ch {
+			}
+		}
+	}
+}
+
+func TestMap_SafeIterator(t *testing.T) {
+	modes := []IteratorMode{IteratorSnapshot, IteratorWeak}
+	for _, tm := range testMaps {
+		m := tm.new()
+		for i := 0; i < 100; i++ {
+			m.Put(fmt.Sprintf("%03d", i), i)
+		}
+
+		for _, mode := range modes {
+			it := m.SafeIterator(mode)
+			m.Delete("050")
+			m.Put("100", 100)
+
+			keyList := make([]string, 0)
+			for it.HasNext() {
+				k, _ := it.Next()
+				keyList = append(keyList, k)
+			}
+			if it.Err() != nil {
+				t.Fatalf("%s mode %d err %v", tm.name, mode, it.Err())
+			}
+
+			// snapshot see no later write, weak see write after the last key
+			want := []string{"050", "099"}
+			if mode == IteratorWeak {
+				want = []string{"051", "100"}
+			}
+			if len(keyList) != 100 || keyList[50] != want[0] || keyList[99] != want[1] {
+				t.Fatalf("%s mode %d get %d keys, want %v", tm.name, mode, len(keyList), want)
+			}
+
+			m.Put("050", 50)
+			m.Delete("100")
+		}
+	}
+}
+
+// go test -race -run TestMap_SafeIteratorConcurrent
+// even keys never change, odd keys put and delete by writers, every iteration should see all even keys once in order
+func TestMap_SafeIteratorConcurrent(t *testing.T) {
+	const num = 2000
+	for _, tm := range testMaps {
+		m := tm.new()
+		for i := 0; i < num; i += 2 {
+			m.Put(fmt.Sprintf("%05d", i), i)
+		}
+
+		stop := make(chan struct{})
+		wg := sync.WaitGroup{}
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+
+				r := rand.New(rand.NewSource(int64(g)))
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+
+					key := fmt.Sprintf("%05d", r.Intn(num/2)*2+1)
+					if r.Intn(2) == 0 {
+						m.Put(key, key)
+					} else {
+						m.Delete(key)
+					}
+				}
+			}(g)
+		}
+
+		for round := 0; round < 10; round++ {
+			for _, mode := range []IteratorMode{IteratorSnapshot, IteratorWeak} {
+				it := m.SafeIterator(mode)
+				even := 0
+				last := ""
+				for it.HasNext() {
+					k, _ := it.Next()
+					if k <= last {
+						t.Fatalf("%s mode %d get %s after %s", tm.name, mode, k, last)
+					}
+					last = k
+
+					var i int
+					_, _ = fmt.Sscanf(k, "%d", &i)
+					if i%2 == 0 {
+						if i != even*2 {
+							t.Fatalf("%s mode %d miss key %05d", tm.name, mode, even*2)
+						}
+						even++
+					}
+				}
+
+				if even != num/2 || it.Err() != nil {
+					t.Fatalf("%s mode %d see %d even keys, err %v", tm.name, mode, even, it.Err())
+				}
+			}
+		}
+
+		close(stop)
+		wg.Wait()
+	}
+}
+
+// writer put round num to all keys from min to max again and again
+// snapshot iterator is point in time, keys before the writer see the round, keys after it see the round before
+func TestMap_SafeIteratorSnapshotConsistent(t *testing.T) {
+	const num = 2000
+	for _, tm := range testMaps {
+		m := tm.new()
+		for i := 0; i < num; i++ {
+			m.Put(fmt.Sprintf("%05d", i), 0)
+		}
+
+		stop := make(chan struct{})
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for round := 1; ; round++ {
+				for i := 0; i < num; i++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+
+					m.Put(fmt.Sprintf("%05d", i), round)
+				}
+			}
+		}()
+
+		for round := 0; round < 200; round++ {
+			it := m.SafeIterator(IteratorSnapshot)
+			first, last, n := -1, -1, 0
+			for it.HasNext() {
+				k, v := it.Next()
+				if first == -1 {
+					first = v.(int)
+				} else if v.(int) > last || v.(int) < first-1 {
+					t.Fatalf("%s snapshot not point in time, %s get round %d after round %d, first key round %d", tm.name, k, v, last, first)
+				}
+				last = v.(int)
+				n++
+			}
+
+			if n != num {
+				t.Fatalf("%s snapshot see %d keys", tm.name, n)
+			}
+		}
+
+		close(stop)
+		wg.Wait()
+	}
+}
+
+func TestMap_Stats(t *testing.T) {
+	for _, tm := range testMaps {
+		m := tm.new()
+
+		var gets, puts, deletes int64
+		r := rand.New(rand.NewSource(int64(randNum)))
+		for i := 0; i < 3000; i++ {
+			key := fmt.Sprintf("%d", r.Int63n(500))
+			switch r.Intn(3) {
+			case 0:
+				m.Put(key, i)
+				puts++
+			case 1:
+				if m.Contains(key) {
+					deletes++
+				}
+				m.Delete(key)
+				gets++
+			default:
+				m.Get(key)
+				gets++
+			}
+		}
+
+		s := m.Stats()
+		if s.Len != m.Len() || s.Height != m.Height() || s.Height == 0 {
+			t.Fatalf("%s stats len %d height %d, map len %d height %d", tm.name, s.Len, s.Height, m.Len(), m.Height())
+		}
+		if s.Gets != gets || s.Puts != puts || s.Deletes != deletes || s.Compares == 0 {
+			t.Fatalf("%s stats %+v, want gets %d puts %d deletes %d", tm.name, s, gets, puts, deletes)
+		}
+
+		switch tm.name {
+		case "rbt", "llrb", "sharded":
+			if s.LeftRotations == 0 || s.RightRotations == 0 || s.Recolors == 0 || s.Rebalances != 0 {
+				t.Fatalf("%s stats %+v", tm.name, s)
+			}
+		case "avl", "avl recursion", "wbt":
+			if s.LeftRotations == 0 || s.RightRotations == 0 || s.Rebalances == 0 || s.Recolors != 0 {
+				t.Fatalf("%s stats %+v", tm.name, s)
+			}
+		case "scapegoat":
+			// random keys may never go too deep, rebuild is not sure
+			if s.LeftRotations != 0 || s.RightRotations != 0 || s.Recolors != 0 {
+				t.Fatalf("%s stats %+v", tm.name, s)
+			}
+		}
+
+		// skip list, treap and splay tree have no hard bound
+		if tm.name != "skiplist" && tm.name != "treap" && tm.name != "splay" && float64(s.Height) > s.HeightBound {
+			t.Fatalf("%s height %d out of bound %f", tm.name, s.Height, s.HeightBound)
+		}
+
+		// height follows every write, kept in nodes or walked when asked
+		for i := 0; i < 1000; i++ {
+			m.Put(fmt.Sprintf("h%d", i), i)
+		}
+		if h := m.Stats().Height; h <= s.Height {
+			t.Fatalf("%s height %d not grow from %d", tm.name, h, s.Height)
+		}
+
+		var v expvar.Var = StatsVar(m.Stats)
+		var got Stats
+		if err := json.Unmarshal([]byte(v.String()), &got); err != nil || got.Len != m.Len() {
+			t.Fatalf("%s expvar get %s, err %v", tm.name, v.String(), err)
+		}
+	}
+}
+
+func TestTreapMap_Seed(t *testing.T) {
+	build := func(seed int64) Map {
+		m := NewTreapMap(seed)
+		r := rand.New(rand.NewSource(int64(randNum)))
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("%d", r.Int63n(500))
+			if r.Intn(3) == 0 {
+				m.Delete(key)
+			} else {
+				m.Put(key, i)
+			}
+		}
+
+		if !m.Check() {
+			t.Fatalf("seed %d not a treap", seed)
+		}
+		return m
+	}
+
+	// same seed same shape, layer order show the shape
+	m1, m2, m3 := build(1), build(1), build(2)
+	if strings.Join(m1.KeyList(), ",") != strings.Join(m2.KeyList(), ",") || m1.Height() != m2.Height() {
+		t.Fatalf("same seed get different shape, height %d %d", m1.Height(), m2.Height())
+	}
+
+	if strings.Join(m1.KeyList(), ",") == strings.Join(m3.KeyList(), ",") {
+		t.Fatalf("different seed get same shape")
+	}
+
+	if strings.Join(m1.KeySortedList(), ",") != strings.Join(m3.KeySortedList(), ",") {
+		t.Fatalf("different seed get different keys")
+	}
+}
+
+func TestTreapMap_SplitJoin(t *testing.T) {
+	m := NewTreapMap(int64(randNum))
+	for i := 10; i < 100; i++ {
+		key := fmt.Sprintf("%d", i)
+		m.Put(key, key)
+	}
+
+	less, ge := m.Split("50")
+	if !less.Check() || !ge.Check() || less.Len() != 40 || ge.Len() != 50 {
+		t.Fatalf("split len %d %d", less.Len(), ge.Len())
+	}
+	if k, _, _ := less.MaxKey(); k != "49" {
+		t.Fatalf("less max key %s", k)
+	}
+	if k, _, _ := ge.MinKey(); k != "50" {
+		t.Fatalf("greater or equal min key %s", k)
+	}
+
+	// split maps share nodes with map, write on any of them not change others
+	less.Put("10", "less")
+	ge.Delete("50")
+	m.Put("99", "m")
+	if v, _ := m.Get("10"); v != "10" || !m.Contains("50") || m.Len() != 90 || !m.Check() {
+		t.Fatalf("map changed by split maps")
+	}
+	if v, _ := ge.Get("99"); v != "99" || !ge.Check() || !less.Check() {
+		t.Fatalf("split map changed by map")
+	}
+
+	if _, err := ge.Join(less); err != ErrJoinOrder {
+		t.Fatalf("join out of order get %v", err)
+	}
+
+	joined, err := less.Join(ge)
+	if err != nil || !joined.Check() || joined.Len() != 89 || joined.Contains("50") {
+		t.Fatalf("join len %d err %v", joined.Len(), err)
+	}
+	if k, _, _ := joined.Select(40); k != "51" {
+		t.Fatalf("joined select 40 get %s", k)
+	}
+
+	joined.Put("50", "joined")
+	if less.Len() != 40 || ge.Len() != 49 || ge.Contains("50") || !less.Check() || !ge.Check() {
+		t.Fatalf("split maps changed by joined map")
+	}
+
+	// split at the ends
+	empty, all := m.Split("")
+	if empty.Len() != 0 || all.Len() != m.Len() {
+		t.Fatalf("split at min get %d %d", empty.Len(), all.Len())
+	}
+	if joined, err = empty.Join(all); err != nil || joined.Len() != m.Len() || !joined.Check() {
+		t.Fatalf("join empty get %d %v", joined.Len(), err)
+	}
+
+	// split and join not change seed of map, it build the same shape as a map never split
+	twin := NewTreapMap(int64(randNum))
+	for i := 10; i < 100; i++ {
+		key := fmt.Sprintf("%d", i)
+		twin.Put(key, key)
+	}
+	for i := 100; i < 200; i++ {
+		key := fmt.Sprintf("%d", i)
+		m.Put(key, key)
+		twin.Put(key, key)
+	}
+	if strings.Join(m.KeyList(), ",") != strings.Join(twin.KeyList(), ",") {
+		t.Fatalf("split or join change shape of map")
+	}
+}
+
+func TestSplayMap_Splay(t *testing.T) {
+	m := NewSplayMap()
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("%d", rand.Intn(500))
+		m.Put(key, key)
+	}
+
+	keys := m.KeySortedList()
+	snapshot := m.Snapshot()
+	shape := strings.Join(snapshot.KeyList(), ",")
+	it := m.AscendIterator()
+
+	// key get is root, layer order show it first
+	for i := 0; i < 100; i++ {
+		key := keys[rand.Intn(len(keys))]
+		if _, exist := m.Get(key); !exist || m.KeyList()[0] != key || !m.Check() {
+			t.Fatalf("get %s not splay to root", key)
+		}
+
+		if _, exist := snapshot.Get(key); !exist {
+			t.Fatalf("snapshot miss %s", key)
+		}
+	}
+
+	// get on snapshot not splay, splay of tree copy nodes and not change snapshot and iterator
+	if strings.Join(snapshot.KeyList(), ",") != shape || !snapshot.Check() {
+		t.Fatal("snapshot shape change")
+	}
+
+	got := make([]string, 0, len(keys))
+	for it.HasNext() {
+		k, _ := it.Next()
+		got = append(got, k)
+	}
+	if strings.Join(got, ",") != strings.Join(keys, ",") {
+		t.Fatalf("iterator broken by splay, got %d keys", len(got))
+	}
+}
+
+func TestLLRBMap_Check(t *testing.T) {
+	m := NewLLRBMap()
+	for i := 0; i < 100; i++ {
+		m.Put(fmt.Sprintf("%03d", i), i)
+	}
+
+	if !m.Check() {
+		t.Fatal("not a llrb tree")
+	}
+
+	// a right red link is fine in rbTree but not in llrb
+	tree := m.(*llrbTree)
+	x := tree.root
+	for x.right != nil {
+		x = x.right
+	}
+	x.color = RED
+	if m.Check() {
+		t.Fatal("right red link not found")
+	}
+}
+
+func TestScapegoatMap_Alpha(t *testing.T) {
+	for _, alpha := range []float64{0.51, 0.7, 0.99, 0.5, 1} {
+		m := NewScapegoatMap(alpha)
+
+		// sorted keys go deep fast, scapegoat must be rebuilt
+		for i := 0; i < 5000; i++ {
+			m.Put(fmt.Sprintf("%05d", i), i)
+		}
+		for i := 0; i < 4000; i++ {
+			m.Delete(fmt.Sprintf("%05d", rand.Intn(5000)))
+		}
+
+		s := m.Stats()
+		if !m.Check() || s.Rebalances == 0 || float64(s.Height) > s.HeightBound {
+			t.Fatalf("alpha %f stats %+v", alpha, s)
+		}
+
+		// out of (0.5, 1) use default
+		if tree := m.(*scapegoatTree); (alpha <= 0.5 || alpha >= 1) && tree.alpha != scapegoatAlpha {
+			t.Fatalf("alpha %f not fall back", alpha)
+		}
+	}
+}
+
+func TestWBTMap_Alpha(t *testing.T) {
+	for _, alpha := range []float64{0.19, 0.25, wbtAlphaMax, 2.0 / 11, 0.3} {
+		m := NewWBTMapWithAlpha(alpha)
+		for i := 0; i < 5000; i++ {
+			m.Put(fmt.Sprintf("%05d", i), i)
+		}
+		for i := 0; i < 4000; i++ {
+			m.Delete(fmt.Sprintf("%05d", rand.Intn(5000)))
+		}
+
+		s := m.Stats()
+		if !m.Check() || s.Rebalances == 0 || float64(s.Height) > s.HeightBound {
+			t.Fatalf("alpha %f stats %+v", alpha, s)
+		}
+
+		// out of (2/11, 1-sqrt(2)/2] use default
+		if tree := m.(*wbtTree); (alpha <= wbtAlphaMin || alpha > wbtAlphaMax) && tree.alpha != wbtAlpha {
+			t.Fatalf("alpha %f not fall back", alpha)
+		}
+	}
+}
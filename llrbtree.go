@@ -0,0 +1,1110 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// left-leaning red-black tree, short call llrb
+// refer Sedgewick, Algorithms 4th, 对应 2-3 树，红链接只能向左，比 rbTree 对应的 2-3-4 树少很多情况
+// 没有父亲指针，递归插入删除，回溯时自底向上修复，写时复制路径
+type llrbTree struct {
+	modCount     int64      // num of add or delete key, iterator use it to fail fast
+	c            comparator // tree key compare
+	root         *llrbNode  // tree root node
+	len          int64      // tree key pairs num
+	gen          uint64     // node gen less than it is shared by snapshot, copy before change
+	watch        *watchHub  // subscribers of change, nil until first watch
+	stats        mapStats   // live counters
+	sync.RWMutex            // lock for concurrent safe, read lock for lookup
+}
+
+// llrb node
+type llrbNode struct {
+	k      string      // key
+	v      interface{} // value
+	left   *llrbNode   // left tree
+	right  *llrbNode   // right tree
+	color  bool        // color of parent point to this node
+	height int32       // height of the sub tree which root is this node, change with size
+	size   int64       // key pairs num of the sub tree which root is this node
+	gen    uint64      // gen of tree when node created or copied
+}
+
+// NewLLRBMap new a left-leaning red-black tree map
+func NewLLRBMap() Map {
+	t := new(llrbTree)
+	t.c = comparatorDefault
+	return t
+}
+
+// 节点所在子树的高度，空节点是 0
+func (node *llrbNode) treeHeight() int64 {
+	if node == nil {
+		return 0
+	}
+
+	return int64(node.height)
+}
+
+// Height 根节点记录了整棵树的高度，O(1)
+func (tree *llrbTree) Height() int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.root.treeHeight()
+}
+
+// Stats 实时统计，高度上界和红黑树相同
+func (tree *llrbTree) Stats() Stats {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := tree.stats.load()
+	s.Len = tree.len
+	s.Height = tree.root.treeHeight()
+	s.HeightBound = rbHeightBound(s.Len)
+	return s
+}
+
+// 节点所在子树的节点数量
+func (node *llrbNode) treeSize() int64 {
+	if node == nil {
+		return 0
+	}
+
+	return node.size
+}
+
+// 空节点是黑色
+func (node *llrbNode) isRed() bool {
+	if node == nil {
+		return false
+	}
+
+	return node.color == RED
+}
+
+// 快照共享的节点先复制，调用者负责把复制的节点挂回父亲
+func (tree *llrbTree) own(node *llrbNode) *llrbNode {
+	if node == nil || node.gen == tree.gen {
+		return node
+	}
+
+	n := new(llrbNode)
+	*n = *node
+	n.gen = tree.gen
+	return n
+}
+
+// 设置节点颜色，颜色变了才算一次变色
+func (tree *llrbTree) setColor(node *llrbNode, color bool) {
+	if node.color != color {
+		node.color = color
+		atomic.AddInt64(&tree.stats.recolors, 1)
+	}
+}
+
+// 对某节点左旋转，右红链接转为左红链接，返回新的子树根，h 需要已复制
+func (tree *llrbTree) rotateLeft(h *llrbNode) *llrbNode {
+	atomic.AddInt64(&tree.stats.leftRotations, 1)
+
+	x := tree.own(h.right)
+	h.right = x.left
+	x.left = h
+
+	// x 继承 h 的颜色，h 变为红色
+	x.color = h.color
+	h.color = RED
+
+	// 旋转后 x 接管了 h 整棵子树，h 的节点数量和高度重新计算
+	x.size = h.size
+	h.size = h.left.treeSize() + h.right.treeSize() + 1
+	h.height = nodeHeight(h.left.treeHeight(), h.right.treeHeight())
+	x.height = nodeHeight(x.left.treeHeight(), x.right.treeHeight())
+	return x
+}
+
+// 对某节点右旋转，左红链接转为右红链接，返回新的子树根，h 需要已复制
+func (tree *llrbTree) rotateRight(h *llrbNode) *llrbNode {
+	atomic.AddInt64(&tree.stats.rightRotations, 1)
+
+	x := tree.own(h.left)
+	h.left = x.right
+	x.right = h
+
+	x.color = h.color
+	h.color = RED
+
+	x.size = h.size
+	h.size = h.left.treeSize() + h.right.treeSize() + 1
+	h.height = nodeHeight(h.left.treeHeight(), h.right.treeHeight())
+	x.height = nodeHeight(x.left.treeHeight(), x.right.treeHeight())
+	return x
+}
+
+// 节点和两个儿子都变色，插入时相当于分裂 2-3-4 临时节点，删除时相当于合并，h 需要已复制
+func (tree *llrbTree) flipColors(h *llrbNode) {
+	h.left = tree.own(h.left)
+	h.right = tree.own(h.right)
+	tree.setColor(h, !h.color)
+	tree.setColor(h.left, !h.left.color)
+	tree.setColor(h.right, !h.right.color)
+}
+
+// 回溯时修复节点，右红链接左旋，连续两个左红链接右旋，左右都红则变色
+func (tree *llrbTree) balance(h *llrbNode) *llrbNode {
+	if h.right.isRed() && !h.left.isRed() {
+		h = tree.rotateLeft(h)
+	}
+
+	if h.left.isRed() && h.left.left.isRed() {
+		h = tree.rotateRight(h)
+	}
+
+	if h.left.isRed() && h.right.isRed() {
+		tree.flipColors(h)
+	}
+
+	h.size = h.left.treeSize() + h.right.treeSize() + 1
+	h.height = nodeHeight(h.left.treeHeight(), h.right.treeHeight())
+	return h
+}
+
+// 假设 h 是红色，h.left 和 h.left.left 是黑色，把 h.left 或它的儿子变红
+func (tree *llrbTree) moveRedLeft(h *llrbNode) *llrbNode {
+	tree.flipColors(h)
+	if h.right.left.isRed() {
+		h.right = tree.rotateRight(h.right)
+		h = tree.rotateLeft(h)
+		tree.flipColors(h)
+	}
+
+	return h
+}
+
+// 假设 h 是红色，h.right 和 h.right.left 是黑色，把 h.right 或它的儿子变红
+func (tree *llrbTree) moveRedRight(h *llrbNode) *llrbNode {
+	tree.flipColors(h)
+	if h.left.left.isRed() {
+		h = tree.rotateRight(h)
+		tree.flipColors(h)
+	}
+
+	return h
+}
+
+// Put 左倾红黑树添加元素
+func (tree *llrbTree) Put(key string, value interface{}) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.put(key, value)
+}
+
+// 添加键值对，不加锁，调用者需要先加锁
+func (tree *llrbTree) put(key string, value interface{}) {
+	var n int64
+	tree.root = tree.insert(tree.root, key, value, &n)
+
+	// 根节点永远为黑
+	tree.setColor(tree.root, BLACK)
+	tree.stats.compare(key, n)
+}
+
+// 递归插入到以 h 为根的子树，返回新的子树根，n 记录比较次数
+func (tree *llrbTree) insert(h *llrbNode, key string, value interface{}, n *int64) *llrbNode {
+	if h == nil {
+		// 新节点都是红色，和父亲合成 2-3 树的节点
+		tree.len++
+		atomic.AddInt64(&tree.modCount, 1)
+		atomic.AddInt64(&tree.stats.puts, 1)
+		tree.watch.put(key, value, nil, false)
+		return &llrbNode{
+			k:      key,
+			v:      value,
+			color:  RED,
+			height: 1,
+			size:   1,
+			gen:    tree.gen,
+		}
+	}
+
+	// 快照共享的路径先复制
+	h = tree.own(h)
+
+	*n++
+	cmp := tree.c(key, h.k)
+	if cmp < 0 {
+		h.left = tree.insert(h.left, key, value, n)
+	} else if cmp > 0 {
+		h.right = tree.insert(h.right, key, value, n)
+	} else {
+		// update new value，树形不变，回溯时 balance 什么也不做
+		tree.update(h, value)
+	}
+
+	return tree.balance(h)
+}
+
+// 更新节点的值，节点需要已复制，不加锁
+func (tree *llrbTree) update(node *llrbNode, value interface{}) {
+	old := node.v
+	node.v = value
+	atomic.AddInt64(&tree.stats.puts, 1)
+	tree.watch.put(node.k, value, old, true)
+}
+
+// Delete 左倾红黑树删除元素
+func (tree *llrbTree) Delete(key string) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.deleteKey(key)
+}
+
+// 删除键，返回被删除的值，不加锁，调用者需要先加锁
+// 先查找，键不存在时不修改树，迭代器不会失败
+func (tree *llrbTree) deleteKey(key string) (value interface{}, exist bool) {
+	node := tree.find(key)
+	if node == nil {
+		return
+	}
+
+	value = node.v
+	tree.root = tree.delete(tree.prepareRoot(), key)
+	tree.deleted(key, value)
+	return value, true
+}
+
+// 删除前如果根的两个儿子都是黑色，根变红，向下删除时总能借到红链接
+func (tree *llrbTree) prepareRoot() *llrbNode {
+	tree.root = tree.own(tree.root)
+	if !tree.root.left.isRed() && !tree.root.right.isRed() {
+		tree.setColor(tree.root, RED)
+	}
+
+	return tree.root
+}
+
+// 删除完成，根节点变回黑色
+func (tree *llrbTree) deleted(key string, value interface{}) {
+	if tree.root != nil {
+		tree.setColor(tree.root, BLACK)
+	}
+
+	tree.len--
+	atomic.AddInt64(&tree.modCount, 1)
+	atomic.AddInt64(&tree.stats.deletes, 1)
+	tree.watch.delete(key, value)
+}
+
+// 递归删除以 h 为根的子树中的 key，key 必须存在，返回新的子树根
+// 向下时保证当前节点或它的左儿子是红色，删除的总是 3 节点或 4 节点中的键，回溯时修复
+func (tree *llrbTree) delete(h *llrbNode, key string) *llrbNode {
+	h = tree.own(h)
+	if tree.c(key, h.k) < 0 {
+		if !h.left.isRed() && !h.left.left.isRed() {
+			h = tree.moveRedLeft(h)
+		}
+		h.left = tree.delete(h.left, key)
+	} else {
+		if h.left.isRed() {
+			h = tree.rotateRight(h)
+		}
+
+		// 要删除的是底部节点，直接删掉
+		if tree.c(key, h.k) == 0 && h.right == nil {
+			return nil
+		}
+
+		if !h.right.isRed() && !h.right.left.isRed() {
+			h = tree.moveRedRight(h)
+		}
+
+		if tree.c(key, h.k) == 0 {
+			// 用右子树最小节点补位，再删除右子树最小节点
+			m := h.right.minNode()
+			h.k, h.v = m.k, m.v
+			h.right = tree.deleteMin(h.right)
+		} else {
+			h.right = tree.delete(h.right, key)
+		}
+	}
+
+	return tree.balance(h)
+}
+
+// 删除以 h 为根的子树的最小节点，返回新的子树根
+func (tree *llrbTree) deleteMin(h *llrbNode) *llrbNode {
+	h = tree.own(h)
+	if h.left == nil {
+		return nil
+	}
+
+	if !h.left.isRed() && !h.left.left.isRed() {
+		h = tree.moveRedLeft(h)
+	}
+
+	h.left = tree.deleteMin(h.left)
+	return tree.balance(h)
+}
+
+// 删除以 h 为根的子树的最大节点，返回新的子树根
+func (tree *llrbTree) deleteMax(h *llrbNode) *llrbNode {
+	h = tree.own(h)
+	if h.left.isRed() {
+		h = tree.rotateRight(h)
+	}
+
+	if h.right == nil {
+		return nil
+	}
+
+	if !h.right.isRed() && !h.right.left.isRed() {
+		h = tree.moveRedRight(h)
+	}
+
+	h.right = tree.deleteMax(h.right)
+	return tree.balance(h)
+}
+
+// find key in tree
+func (tree *llrbTree) find(key string) *llrbNode {
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 {
+			break
+		} else if cmp < 0 {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return node
+}
+
+// MinKey find min key pairs
+func (tree *llrbTree) MinKey() (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	node := tree.root.minNode()
+	return node.k, node.v, true
+}
+
+func (node *llrbNode) minNode() *llrbNode {
+	for node.left != nil {
+		node = node.left
+	}
+
+	return node
+}
+
+// MaxKey find max key pairs
+func (tree *llrbTree) MaxKey() (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	node := tree.root.maxNode()
+	return node.k, node.v, true
+}
+
+func (node *llrbNode) maxNode() *llrbNode {
+	for node.right != nil {
+		node = node.right
+	}
+
+	return node
+}
+
+// Floor find the greatest key pairs less than or equal to key
+func (tree *llrbTree) Floor(key string) (floorKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.floor(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Ceiling find the least key pairs greater than or equal to key
+func (tree *llrbTree) Ceiling(key string) (ceilingKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Lower find the greatest key pairs strictly less than key
+func (tree *llrbTree) Lower(key string) (lowerKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.floor(key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Higher find the least key pairs strictly greater than key
+func (tree *llrbTree) Higher(key string) (higherKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// the greatest node less than key, less than or equal to key when inclusive
+func (tree *llrbTree) floor(key string, inclusive bool) *llrbNode {
+	var candidate *llrbNode
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp > 0 {
+			candidate = node
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// the least node greater than key, greater than or equal to key when inclusive
+func (tree *llrbTree) ceiling(key string, inclusive bool) *llrbNode {
+	var candidate *llrbNode
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp < 0 {
+			candidate = node
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// Rank num of keys strictly less than key
+func (tree *llrbTree) Rank(key string) int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	var rank, n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp > 0 {
+			rank += node.left.treeSize() + 1
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return rank
+}
+
+// Select find the i-th smallest key pairs, i start from 0
+func (tree *llrbTree) Select(i int64) (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if i < 0 || i >= tree.root.treeSize() {
+		return
+	}
+
+	node := tree.root
+	for node != nil {
+		leftSize := node.left.treeSize()
+		if i < leftSize {
+			node = node.left
+		} else if i == leftSize {
+			return node.k, node.v, true
+		} else {
+			i = i - leftSize - 1
+			node = node.right
+		}
+	}
+
+	return
+}
+
+// Get 查找指定节点
+func (tree *llrbTree) Get(key string) (value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
+
+	return tree.get(key)
+}
+
+// 查找键的值，不加锁，调用者需要先加锁
+func (tree *llrbTree) get(key string) (value interface{}, exist bool) {
+	if node := tree.find(key); node != nil {
+		return node.v, true
+	}
+
+	return
+}
+
+// Contains 查找指定节点
+func (tree *llrbTree) Contains(key string) (exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
+
+	return tree.find(key) != nil
+}
+
+func (tree *llrbTree) Len() int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.len
+}
+
+func (tree *llrbTree) GetInt(key string) (value int, exist bool, err error) {
+	return getInt(tree.Get, key)
+}
+
+func (tree *llrbTree) GetInt64(key string) (value int64, exist bool, err error) {
+	return getInt64(tree.Get, key)
+}
+
+func (tree *llrbTree) GetString(key string) (value string, exist bool, err error) {
+	return getString(tree.Get, key)
+}
+
+func (tree *llrbTree) GetFloat64(key string) (value float64, exist bool, err error) {
+	return getFloat64(tree.Get, key)
+}
+
+func (tree *llrbTree) GetBytes(key string) (value []byte, exist bool, err error) {
+	return getBytes(tree.Get, key)
+}
+
+func (tree *llrbTree) KeySortedList() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, nil, func(key string, value interface{}) bool {
+			keyList = append(keyList, key)
+			return true
+		})
+	}
+
+	return keyList
+}
+
+func (tree *llrbTree) KeySortedListDesc() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	if tree.root != nil {
+		descend(tree.root, func(key string, value interface{}) bool {
+			keyList = append(keyList, key)
+			return true
+		})
+	}
+
+	return keyList
+}
+
+// Check 验证是不是棵左倾红黑树
+func (tree *llrbTree) Check() bool {
+	if tree == nil || tree.root == nil {
+		return true
+	}
+
+	if tree.root.size != tree.len {
+		fmt.Printf("root size %d != len %d\n", tree.root.size, tree.len)
+		return false
+	}
+
+	// 判断树是否是一棵二分查找树，子树节点数量是否正确
+	if !tree.root.isBST(tree.c, nil, nil) {
+		fmt.Println("is not BST")
+		return false
+	}
+
+	// 根节点是黑色
+	if tree.root.isRed() {
+		fmt.Println("root is red")
+		return false
+	}
+
+	// 判断树是否遵循2-3树，也就是红链接只能向左，不能有连续的两个红链接
+	if !tree.root.is23() {
+		fmt.Println("is not 23 tree")
+		return false
+	}
+
+	// 判断树是否平衡，任意一个节点到空链接，经过的黑色链接数量相同
+	blackNum := 0
+	for x := tree.root; x != nil; x = x.left {
+		if !x.isRed() {
+			blackNum++
+		}
+	}
+
+	if !tree.root.isBalanced(blackNum) {
+		fmt.Println("is not Balanced")
+		return false
+	}
+
+	return true
+}
+
+// 节点所在的子树是否遵循2-3树，比 rbTree 的 is234 多一条：不能有右红链接
+func (node *llrbNode) is23() bool {
+	if node == nil {
+		return true
+	}
+
+	if node.right.isRed() {
+		fmt.Printf("father:%#v,rchild:%#v\n", node, node.right)
+		return false
+	}
+
+	if node.isRed() && node.left.isRed() {
+		fmt.Printf("father:%#v,lchild:%#v\n", node, node.left)
+		return false
+	}
+
+	return node.left.is23() && node.right.is23()
+}
+
+// 节点所在的子树是否平衡，是否有 blackNum 个黑链接
+func (node *llrbNode) isBalanced(blackNum int) bool {
+	if node == nil {
+		return blackNum == 0
+	}
+
+	if !node.isRed() {
+		blackNum--
+	}
+
+	return node.left.isBalanced(blackNum) && node.right.isBalanced(blackNum)
+}
+
+// 节点所在的子树，所有键在 (lo, hi) 之间，nil 表示没有边界，节点数量正确
+func (node *llrbNode) isBST(compare comparator, lo, hi *string) bool {
+	if node == nil {
+		return true
+	}
+
+	if (lo != nil && compare(*lo, node.k) >= 0) || (hi != nil && compare(node.k, *hi) >= 0) {
+		fmt.Printf("key %s is not sorted\n", node.k)
+		return false
+	}
+
+	if node.size != node.left.treeSize()+node.right.treeSize()+1 {
+		fmt.Printf("size %d != %d+%d+1\n", node.size, node.left.treeSize(), node.right.treeSize())
+		return false
+	}
+
+	if node.height != nodeHeight(node.left.treeHeight(), node.right.treeHeight()) {
+		fmt.Printf("height %d, left %d, right %d\n", node.height, node.left.treeHeight(), node.right.treeHeight())
+		return false
+	}
+
+	return node.left.isBST(compare, lo, &node.k) && node.right.isBST(compare, &node.k, hi)
+}
+
+func (node *llrbNode) leftOf() bsTreeNode {
+	if node.left == nil {
+		return nil
+	}
+
+	return node.left
+}
+
+func (node *llrbNode) rightOf() bsTreeNode {
+	if node.right == nil {
+		return nil
+	}
+
+	return node.right
+}
+
+// not check node nil, may be panic, user should deal by oneself
+func (node *llrbNode) values() (key string, value interface{}) {
+	return node.k, node.v
+}
+
+func (tree *llrbTree) KeyList() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	iterator := tree.iterator()
+	for iterator.HasNext() {
+		k, _ := iterator.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList
+}
+
+func (tree *llrbTree) Iterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.iterator()
+}
+
+// layer order iterator, without lock
+func (tree *llrbTree) iterator() MapIterator {
+	q := new(linkQueue)
+	q.bind(&tree.modCount)
+	if tree.root != nil {
+		q.add(tree.root)
+	}
+	return q
+}
+
+// SafeIterator sorted iterator safe under concurrent write, snapshot mode is consistent, weak mode find next key every step
+func (tree *llrbTree) SafeIterator(mode IteratorMode) MapIterator {
+	return newSafeIterator(tree, mode)
+}
+
+// AscendIterator iterator sorted by key, from min to max
+func (tree *llrbTree) AscendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := new(linkStack)
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// DescendIterator iterator sorted by key, from max to min
+func (tree *llrbTree) DescendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := &linkStack{desc: true}
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// Range iterator key between from and to, sorted by key
+func (tree *llrbTree) Range(from, to string, opt RangeOption) MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.rangeIterator(from, to, opt)
+}
+
+// 范围迭代器，不加锁
+func (tree *llrbTree) rangeIterator(from, to string, opt RangeOption) MapIterator {
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newRangeIterator(root, tree.c, from, to, opt)
+	it.bind(&tree.modCount)
+	return it
+}
+
+// Cursor bidirectional cursor, before min key at first
+func (tree *llrbTree) Cursor() Cursor {
+	return newCursor(tree)
+}
+
+// PrefixIterator iterator key with prefix, sorted by key
+func (tree *llrbTree) PrefixIterator(prefix string) (MapIterator, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return it, nil
+}
+
+// KeysWithPrefix key with prefix out to list sorted
+func (tree *llrbTree) KeysWithPrefix(prefix string) ([]string, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keyList := make([]string, 0)
+	for it.HasNext() {
+		k, _ := it.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList, nil
+}
+
+func (tree *llrbTree) prefixIterator(prefix string) (*rangeIterator, error) {
+	if !isDefaultComparator(tree.c) {
+		return nil, ErrPrefixComparator
+	}
+
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newPrefixIterator(root, tree.c, prefix)
+	it.bind(&tree.modCount)
+	return it, nil
+}
+
+// DeleteRange delete keys which from <= key <= to, return num of deleted keys
+func (tree *llrbTree) DeleteRange(from, to string) int64 {
+	return deleteRange(tree, from, to)
+}
+
+// PopMin find min key pairs and delete it
+func (tree *llrbTree) PopMin() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	node := tree.root.minNode()
+	key, value = node.k, node.v
+	tree.root = tree.deleteMin(tree.prepareRoot())
+	tree.deleted(key, value)
+	return key, value, true
+}
+
+// PopMax find max key pairs and delete it
+func (tree *llrbTree) PopMax() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	node := tree.root.maxNode()
+	key, value = node.k, node.v
+	tree.root = tree.deleteMax(tree.prepareRoot())
+	tree.deleted(key, value)
+	return key, value, true
+}
+
+// Ascend walk all key pairs from min to max, stop when fn return false
+func (tree *llrbTree) Ascend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, nil, fn)
+	}
+}
+
+// Descend walk all key pairs from max to min, stop when fn return false
+func (tree *llrbTree) Descend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		descend(tree.root, fn)
+	}
+}
+
+// AscendGreaterOrEqual walk key pairs which pivot <= key, stop when fn return false
+func (tree *llrbTree) AscendGreaterOrEqual(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &pivot, nil, fn)
+	}
+}
+
+// AscendLessThan walk key pairs which key < pivot, stop when fn return false
+func (tree *llrbTree) AscendLessThan(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, &pivot, fn)
+	}
+}
+
+// AscendRange walk key pairs which greaterOrEqual <= key < lessThan, stop when fn return false
+func (tree *llrbTree) AscendRange(greaterOrEqual, lessThan string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &greaterOrEqual, &lessThan, fn)
+	}
+}
+
+func (tree *llrbTree) SetComparator(c comparator) Map {
+	tree.Lock()
+	defer tree.Unlock()
+	if tree.len == 0 {
+		tree.c = c
+	}
+
+	return tree
+}
+
+// Snapshot read only view of tree now, cost O(1)
+// all nodes now are shared with snapshot, later write copy the path rather than change them
+func (tree *llrbTree) Snapshot() ReadOnlyMap {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.gen++
+	return readOnlyMap{&llrbTree{
+		c:    tree.c,
+		root: tree.root,
+		len:  tree.len,
+	}}
+}
+
+// Begin transaction, all writes apply on commit under one lock
+func (tree *llrbTree) Begin() Txn {
+	return beginTxn(tree)
+}
+
+// Compute put value return by fn, delete key when fn return keep false
+func (tree *llrbTree) Compute(key string, fn ComputeFunc) (value interface{}, exist bool) {
+	return tree.computeOp(key, fn.op())
+}
+
+// 加锁后读改写，fn 不修改键时不写入，快照共享的路径不会被复制
+func (tree *llrbTree) computeOp(key string, fn computeOpFunc) (value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	// 写入要在回溯时修复，找到后再向下写一次
+	node := tree.find(key)
+	if node == nil {
+		value, op := fn(nil, false)
+		if op != computePut {
+			return nil, false
+		}
+
+		tree.put(key, value)
+		return value, true
+	}
+
+	value, op := fn(node.v, true)
+	switch op {
+	case computePut:
+		tree.put(key, value)
+		return value, true
+	case computeDelete:
+		tree.root = tree.delete(tree.prepareRoot(), key)
+		tree.deleted(key, node.v)
+		return nil, false
+	}
+
+	return node.v, true
+}
+
+// PutIfAbsent put if key not exist, otherwise return the exist value
+func (tree *llrbTree) PutIfAbsent(key string, value interface{}) (actual interface{}, loaded bool) {
+	return putIfAbsent(tree.computeOp, key, value)
+}
+
+// Replace put only if key exist, return the old value
+func (tree *llrbTree) Replace(key string, value interface{}) (old interface{}, replaced bool) {
+	return replace(tree.computeOp, key, value)
+}
+
+// CompareAndSwap put new only if value of key == old
+func (tree *llrbTree) CompareAndSwap(key string, old, new interface{}) (swapped bool) {
+	return compareAndSwap(tree.computeOp, key, old, new)
+}
+
+// CompareAndDelete delete only if value of key == old
+func (tree *llrbTree) CompareAndDelete(key string, old interface{}) (deleted bool) {
+	return compareAndDelete(tree.computeOp, key, old)
+}
+
+// Watch watch put and delete of key, channel closed after ctx done
+func (tree *llrbTree) Watch(ctx context.Context, key string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, key, false, opt)
+}
+
+// WatchPrefix watch put and delete of keys with prefix
+func (tree *llrbTree) WatchPrefix(ctx context.Context, prefix string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, prefix, true, opt)
+}
@@ -0,0 +1,65 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import "sync"
+
+// tree map guarded by one lock, helpers below lock it and call its primitives without lock
+type lockedTree interface {
+	Map
+	sync.Locker
+	get(key string) (value interface{}, exist bool)             // get value of key without lock
+	put(key string, value interface{})                          // put key pairs without lock
+	deleteKey(key string) (value interface{}, exist bool)       // delete key without lock
+	rangeIterator(from, to string, opt RangeOption) MapIterator // range iterator without lock
+}
+
+// transaction of tree, keys read are checked and all writes apply on commit under one lock
+func beginTxn(tree lockedTree) Txn {
+	return newTxn(tree, func(reads map[string]txnRead, writes map[string]txnWrite) error {
+		tree.Lock()
+		defer tree.Unlock()
+
+		if err := checkReads(tree.get, reads); err != nil {
+			return err
+		}
+
+		for key, w := range writes {
+			if w.deleted {
+				tree.deleteKey(key)
+			} else {
+				tree.put(key, w.value)
+			}
+		}
+
+		return nil
+	})
+}
+
+// delete keys which from <= key <= to under one lock, return num of deleted keys
+func deleteRange(tree lockedTree, from, to string) int64 {
+	tree.Lock()
+	defer tree.Unlock()
+
+	return deleteRangeKeys(tree, from, to)
+}
+
+// delete keys which from <= key <= to without lock, caller should lock first
+func deleteRangeKeys(tree lockedTree, from, to string) int64 {
+	// collect keys first, delete will change the tree
+	keyList := make([]string, 0)
+	it := tree.rangeIterator(from, to, RangeOption{})
+	for it.HasNext() {
+		k, _ := it.Next()
+		keyList = append(keyList, k)
+	}
+
+	for _, k := range keyList {
+		tree.deleteKey(k)
+	}
+
+	return int64(len(keyList))
+}
@@ -7,13 +7,15 @@ package gomap
 
 import (
 	"sync"
+	"sync/atomic"
 )
 
 // use queue implement iterator
 type linkQueue struct {
-	root *linkNode  // 链表起点
-	size int        // 队列的元素数量
-	lock sync.Mutex // 为了并发安全使用的锁
+	root     *linkNode  // 链表起点
+	size     int        // 队列的元素数量
+	lock     sync.Mutex // 为了并发安全使用的锁
+	modGuard            // fail fast when map modified
 }
 
 // link node
@@ -24,6 +26,11 @@ type linkNode struct {
 
 // HasNext has next, queue size > 0
 func (queue *linkQueue) HasNext() bool {
+	// map modified, stop here
+	if !queue.check() {
+		return false
+	}
+
 	if queue.size > 0 {
 		return true
 	}
@@ -32,6 +39,11 @@ func (queue *linkQueue) HasNext() bool {
 }
 
 func (queue *linkQueue) Next() (key string, value interface{}) {
+	// map modified, node in queue may be dangling, Err will return ErrConcurrentModification
+	if !queue.check() {
+		return
+	}
+
 	// 不断出队列
 	element := queue.remove()
 
@@ -107,4 +119,37 @@ func (queue *linkQueue) remove() bsTreeNode {
 	queue.size--
 
 	return v
+}
+
+// check map is not modified since iterator created
+// like java TreeMap, iterator fail fast when add or delete key
+type modGuard struct {
+	modCount *int64 // point to modCount of tree, nil means not check
+	expect   int64  // modCount when iterator created
+	err      error  // ErrConcurrentModification when map modified
+}
+
+// bind iterator to modCount of tree, should call with tree lock
+func (g *modGuard) bind(modCount *int64) {
+	g.modCount = modCount
+	g.expect = atomic.LoadInt64(modCount)
+}
+
+// map is not modified
+func (g *modGuard) check() bool {
+	if g.err != nil {
+		return false
+	}
+
+	if g.modCount != nil && atomic.LoadInt64(g.modCount) != g.expect {
+		g.err = ErrConcurrentModification
+		return false
+	}
+
+	return true
+}
+
+// Err ErrConcurrentModification when map modified during iterate, iterator stop
+func (g *modGuard) Err() error {
+	return g.err
 }
\ No newline at end of file
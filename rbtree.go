@@ -6,9 +6,11 @@
 package gomap
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -24,10 +26,14 @@ func ReflectError(v interface{}) error {
 // red-black tree, short call rbt
 // refer Java TreeMap
 type rbTree struct {
-	c          comparator // tree key compare
-	root       *rbTNode   // tree root node
-	len        int64      // tree key pairs num
-	sync.Mutex            // lock for concurrent safe
+	modCount     int64      // num of add or delete key, iterator use it to fail fast
+	c            comparator // tree key compare
+	root         *rbTNode   // tree root node
+	len          int64      // tree key pairs num
+	gen          uint64     // node gen less than it is shared by snapshot, copy before change
+	watch        *watchHub  // subscribers of change, nil until first watch
+	stats        mapStats   // live counters
+	sync.RWMutex            // lock for concurrent safe, read lock for lookup
 }
 
 // rbt node
@@ -39,24 +45,64 @@ type rbTNode struct {
 	right  *rbTNode    // right tree
 	parent *rbTNode    // node's parent
 	color  bool        // color of parent point to this node
+	height int32       // height of the sub tree which root is this node, change with size
+	size   int64       // key pairs num of the sub tree which root is this node
+	gen    uint64      // gen of tree when node created or copied
 }
 
-func (node *rbTNode) height() int64 {
+// 节点所在子树的高度，空节点是 0
+func (node *rbTNode) treeHeight() int64 {
 	if node == nil {
 		return 0
 	}
 
-	lh := node.left.height()
-	rh := node.right.height()
-	if lh > rh {
-		return lh + 1
-	} else {
-		return rh + 1
-	}
+	return int64(node.height)
 }
 
+// Height 根节点记录了整棵树的高度，O(1)
 func (tree *rbTree) Height() int64 {
-	return tree.root.height()
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.root.treeHeight()
+}
+
+// Stats 实时统计，计数器用原子操作，不需要写锁
+func (tree *rbTree) Stats() Stats {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.stat()
+}
+
+// 统计，不加锁，调用者需要先加锁
+func (tree *rbTree) stat() Stats {
+	s := tree.stats.load()
+	s.Len = tree.len
+	s.Height = tree.root.treeHeight()
+	s.HeightBound = rbHeightBound(s.Len)
+	return s
+}
+
+// 从 node 开始向上重新计算高度，高度不变时上面的祖先也不变，节点需要已复制
+func (tree *rbTree) fixHeight(node *rbTNode) {
+	for ; node != nil; node = node.parent {
+		h := nodeHeight(node.left.treeHeight(), node.right.treeHeight())
+		if h == node.height {
+			return
+		}
+
+		node.height = h
+	}
+}
+
+// 节点所在子树的节点数量
+func (node *rbTNode) treeSize() int64 {
+	if node == nil {
+		return 0
+	}
+
+	return node.size
 }
 
 // is rbt node is red
@@ -94,10 +140,11 @@ func rightOf(node *rbTNode) *rbTNode {
 	return node.right
 }
 
-// 设置节点颜色
-func setColor(node *rbTNode, color bool) {
-	if node != nil {
+// 设置节点颜色，颜色变了才算一次变色
+func (tree *rbTree) setColor(node *rbTNode, color bool) {
+	if node != nil && node.color != color {
 		node.color = color
+		atomic.AddInt64(&tree.stats.recolors, 1)
 	}
 }
 
@@ -114,6 +161,11 @@ func (tree *rbTree) SetComparator(c comparator) Map {
 // 对某节点左旋转
 func (tree *rbTree) rotateLeft(h *rbTNode) {
 	if h != nil {
+		atomic.AddInt64(&tree.stats.leftRotations, 1)
+
+		// 快照共享的节点先复制
+		h = tree.own(h)
+		tree.own(h.right)
 
 		// 看图理解
 		x := h.right
@@ -133,12 +185,29 @@ func (tree *rbTree) rotateLeft(h *rbTNode) {
 		}
 		x.left = h
 		h.parent = x
+
+		// 旋转后 x 接管了 h 整棵子树，h 的节点数量重新计算
+		x.size = h.size
+		h.size = h.left.treeSize() + h.right.treeSize() + 1
+
+		// h 和 x 的高度重新计算，子树高度变了再向上修正祖先
+		old := h.height
+		h.height = nodeHeight(h.left.treeHeight(), h.right.treeHeight())
+		x.height = nodeHeight(x.left.treeHeight(), x.right.treeHeight())
+		if x.height != old {
+			tree.fixHeight(x.parent)
+		}
 	}
 }
 
 // 对某节点右旋转
 func (tree *rbTree) rotateRight(h *rbTNode) {
 	if h != nil {
+		atomic.AddInt64(&tree.stats.rightRotations, 1)
+
+		// 快照共享的节点先复制
+		h = tree.own(h)
+		tree.own(h.left)
 
 		// 看图理解
 		x := h.left
@@ -158,6 +227,18 @@ func (tree *rbTree) rotateRight(h *rbTNode) {
 		}
 		x.right = h
 		h.parent = x
+
+		// 旋转后 x 接管了 h 整棵子树，h 的节点数量重新计算
+		x.size = h.size
+		h.size = h.left.treeSize() + h.right.treeSize() + 1
+
+		// h 和 x 的高度重新计算，子树高度变了再向上修正祖先
+		old := h.height
+		h.height = nodeHeight(h.left.treeHeight(), h.right.treeHeight())
+		x.height = nodeHeight(x.left.treeHeight(), x.right.treeHeight())
+		if x.height != old {
+			tree.fixHeight(x.parent)
+		}
 	}
 }
 
@@ -166,17 +247,17 @@ func (tree *rbTree) Put(key string, value interface{}) {
 	// add lock
 	tree.Lock()
 	defer tree.Unlock()
+
+	tree.put(key, value)
+}
+
+// 添加键值对，不加锁，调用者需要先加锁
+func (tree *rbTree) put(key string, value interface{}) {
 	//fmt.Println("add,", key)
 
 	// 根节点为空
 	if tree.root == nil {
-		// 根节点都是黑色
-		tree.root = &rbTNode{
-			k:     key,
-			v:     value,
-			color: BLACK,
-		}
-		tree.len = 1
+		tree.insert(nil, 0, key, value)
 		return
 	}
 
@@ -189,9 +270,13 @@ func (tree *rbTree) Put(key string, value interface{}) {
 	// 辅助变量，为了知道元素最后要插到左边还是右边
 	var cmp int64 = 0
 
+	// 比较次数
+	var n int64
+
 	for {
 		parent = t
 
+		n++
 		cmp = tree.c(key, t.k)
 		if cmp < 0 {
 			// 比当前节点小，往左子树插入
@@ -201,7 +286,8 @@ func (tree *rbTree) Put(key string, value interface{}) {
 			t = t.right
 		} else {
 			// update new value
-			t.v = value
+			tree.stats.compare(key, n)
+			tree.update(t, value)
 			return
 		}
 
@@ -211,11 +297,38 @@ func (tree *rbTree) Put(key string, value interface{}) {
 		}
 	}
 
-	// 新节点，它要插入到 parent下面
+	tree.stats.compare(key, n)
+	tree.insert(parent, cmp, key, value)
+}
+
+// 新节点插入到 parent 下面，cmp 小于 0 插到左边，parent 为空时作为根节点，不加锁
+func (tree *rbTree) insert(parent *rbTNode, cmp int64, key string, value interface{}) {
+	if parent == nil {
+		// 根节点都是黑色
+		tree.root = &rbTNode{
+			k:      key,
+			v:      value,
+			color:  BLACK,
+			height: 1,
+			size:   1,
+			gen:    tree.gen,
+		}
+		tree.len = 1
+		atomic.AddInt64(&tree.modCount, 1)
+		atomic.AddInt64(&tree.stats.puts, 1)
+		tree.watch.put(key, value, nil, false)
+		return
+	}
+
+	// 新节点，它要插入到 parent下面，快照共享的路径先复制
+	parent = tree.own(parent)
 	newNode := &rbTNode{
 		k:      key,
 		v:      value,
 		parent: parent,
+		height: 1,
+		size:   1,
+		gen:    tree.gen,
 	}
 	if cmp < 0 {
 		// 知道要从左边插进去
@@ -225,11 +338,29 @@ func (tree *rbTree) Put(key string, value interface{}) {
 		parent.right = newNode
 	}
 
+	// 新节点的祖先们，子树节点数量都加1，高度可能加1
+	for p := parent; p != nil; p = p.parent {
+		p.size++
+	}
+	tree.fixHeight(parent)
+
 	// 插入新节点后，可能破坏了红黑树特征，需要修复，核心函数
 	tree.fixAfterInsertion(newNode)
 
 	// len add 1
 	tree.len++
+	atomic.AddInt64(&tree.modCount, 1)
+	atomic.AddInt64(&tree.stats.puts, 1)
+	tree.watch.put(key, value, nil, false)
+}
+
+// 更新节点的值，不加锁
+func (tree *rbTree) update(node *rbTNode, value interface{}) {
+	node = tree.own(node)
+	old := node.v
+	node.v = value
+	atomic.AddInt64(&tree.stats.puts, 1)
+	tree.watch.put(node.k, value, old, true)
 }
 
 // 调整新插入的节点，自底而上
@@ -247,9 +378,9 @@ func (tree *rbTree) fixAfterInsertion(node *rbTNode) {
 
 			// 图例3左边部分，叔叔是红节点，祖父变色，也就是父亲和叔叔变黑，祖父变红
 			if isRed(uncle) {
-				setColor(parentOf(node), BLACK)
-				setColor(uncle, BLACK)
-				setColor(parentOf(parentOf(node)), RED)
+				tree.setColor(parentOf(node), BLACK)
+				tree.setColor(tree.own(uncle), BLACK)
+				tree.setColor(parentOf(parentOf(node)), RED)
 				// 还要向上递归
 				node = parentOf(parentOf(node))
 			} else {
@@ -260,8 +391,8 @@ func (tree *rbTree) fixAfterInsertion(node *rbTNode) {
 				}
 
 				// 变色，并对祖父进行右旋
-				setColor(parentOf(node), BLACK)
-				setColor(parentOf(parentOf(node)), RED)
+				tree.setColor(parentOf(node), BLACK)
+				tree.setColor(parentOf(parentOf(node)), RED)
 				tree.rotateRight(parentOf(parentOf(node)))
 			}
 		} else {
@@ -271,9 +402,9 @@ func (tree *rbTree) fixAfterInsertion(node *rbTNode) {
 
 			// 图例3右边部分，叔叔是红节点，祖父变色，也就是父亲和叔叔变黑，祖父变红
 			if isRed(uncle) {
-				setColor(parentOf(node), BLACK)
-				setColor(uncle, BLACK)
-				setColor(parentOf(parentOf(node)), RED)
+				tree.setColor(parentOf(node), BLACK)
+				tree.setColor(tree.own(uncle), BLACK)
+				tree.setColor(parentOf(parentOf(node)), RED)
 				// 还要向上递归
 				node = parentOf(parentOf(node))
 			} else {
@@ -284,15 +415,15 @@ func (tree *rbTree) fixAfterInsertion(node *rbTNode) {
 				}
 
 				// 变色，并对祖父进行左旋
-				setColor(parentOf(node), BLACK)
-				setColor(parentOf(parentOf(node)), RED)
+				tree.setColor(parentOf(node), BLACK)
+				tree.setColor(parentOf(parentOf(node)), RED)
 				tree.rotateLeft(parentOf(parentOf(node)))
 			}
 		}
 	}
 
 	// 根节点永远为黑
-	tree.root.color = BLACK
+	tree.setColor(tree.root, BLACK)
 }
 
 // Delete 普通红黑树删除元素
@@ -300,6 +431,11 @@ func (tree *rbTree) Delete(key string) {
 	tree.Lock()
 	defer tree.Unlock()
 
+	tree.deleteKey(key)
+}
+
+// 删除键，返回被删除的值，不加锁，调用者需要先加锁
+func (tree *rbTree) deleteKey(key string) (value interface{}, exist bool) {
 	if tree.root == nil {
 		return
 	}
@@ -311,11 +447,26 @@ func (tree *rbTree) Delete(key string) {
 		return
 	}
 
+	return tree.deleteNode(node), true
+}
+
+// 删除树上的节点，返回节点的值，不加锁
+func (tree *rbTree) deleteNode(node *rbTNode) (value interface{}) {
+	// 快照共享的路径先复制
+	node = tree.own(node)
+
+	// 删除时节点的键值可能被后驱节点替换，先保存
+	key, value := node.k, node.v
+
 	//fmt.Println("delete,", key)
 	// 删除该节点
 	tree.delete(node)
 
 	tree.len--
+	atomic.AddInt64(&tree.modCount, 1)
+	atomic.AddInt64(&tree.stats.deletes, 1)
+	tree.watch.delete(key, value)
+	return value
 }
 
 // 删除节点核心函数
@@ -328,6 +479,8 @@ func (tree *rbTree) delete(node *rbTNode) {
 			s = s.left
 		}
 
+		s = tree.own(s)
+
 		// 删除的叶子节点找到了，删除内部节点转为删除叶子节点
 		node.k = s.k
 		node.v = s.v
@@ -344,7 +497,13 @@ func (tree *rbTree) delete(node *rbTNode) {
 			replacement = node.right
 		}
 
+		// 被删除节点的祖先们，子树节点数量都减1
+		for p := node.parent; p != nil; p = p.parent {
+			p.size--
+		}
+
 		// 替换开始，子树的唯一节点替代被删除的内部节点
+		replacement = tree.own(replacement)
 		replacement.parent = node.parent
 
 		if node.parent == nil {
@@ -358,6 +517,9 @@ func (tree *rbTree) delete(node *rbTNode) {
 			node.parent.right = replacement
 		}
 
+		// 唯一子节点上来了，祖先们的高度可能减1
+		tree.fixHeight(replacement.parent)
+
 		// delete this node
 		node.parent = nil
 		node.right = nil
@@ -374,7 +536,7 @@ func (tree *rbTree) delete(node *rbTNode) {
 		// 单子树时删除的节点绝对是黑色的，而其唯一子节点必然是红色的
 		// 现在唯一子节点替换了被删除节点，该节点要变为黑色
 		// now son replace it's father, just change color to black
-		replacement.color = BLACK
+		tree.setColor(replacement, BLACK)
 
 		//// 要删除的节点，是一个黑节点，删除后会破坏平衡，需要进行调整，调整成可以删除的状态
 		//if !isRed(node) {
@@ -398,6 +560,11 @@ func (tree *rbTree) delete(node *rbTNode) {
 		tree.fixAfterDeletion(node)
 	}
 
+	// 被删除叶子节点的祖先们，子树节点数量都减1
+	for p := node.parent; p != nil; p = p.parent {
+		p.size--
+	}
+
 	// 现在可以删除叶子节点了
 	if node == node.parent.left {
 		node.parent.left = nil
@@ -405,6 +572,7 @@ func (tree *rbTree) delete(node *rbTNode) {
 		node.parent.right = nil
 	}
 
+	tree.fixHeight(node.parent)
 	node.parent = nil
 }
 
@@ -416,34 +584,34 @@ func (tree *rbTree) fixAfterDeletion(node *rbTNode) {
 		// 要删除的节点在父亲左边，对应图例1，2
 		if node == leftOf(parentOf(node)) {
 			// 找出兄弟
-			brother := rightOf(parentOf(node))
+			brother := tree.own(rightOf(parentOf(node)))
 
 			// 兄弟是红色的，对应图例1，那么兄弟变黑，父亲变红，然后对父亲左旋，进入图例21,22,23
 			if isRed(brother) {
-				setColor(brother, BLACK)
-				setColor(parentOf(node), RED)
+				tree.setColor(brother, BLACK)
+				tree.setColor(parentOf(node), RED)
 				tree.rotateLeft(parentOf(node))
-				brother = rightOf(parentOf(node)) // 图例1调整后进入图例21,22,23，兄弟此时变了
+				brother = tree.own(rightOf(parentOf(node))) // 图例1调整后进入图例21,22,23，兄弟此时变了
 			}
 
 			// 兄弟是黑色的，对应图例21，22，23
 			// 兄弟的左右儿子都是黑色，进入图例23，将兄弟设为红色，父亲所在的子树作为整体，当作删除的节点，继续向上递归
 			if !isRed(leftOf(brother)) && !isRed(rightOf(brother)) {
-				setColor(brother, RED)
+				tree.setColor(brother, RED)
 				node = parentOf(node)
 			} else {
 				// 兄弟的右儿子是黑色，进入图例22，将兄弟设为红色，兄弟的左儿子设为黑色，对兄弟右旋，进入图例21
 				if !isRed(rightOf(brother)) {
-					setColor(leftOf(brother), BLACK)
-					setColor(brother, RED)
+					tree.setColor(tree.own(leftOf(brother)), BLACK)
+					tree.setColor(brother, RED)
 					tree.rotateRight(brother)
-					brother = rightOf(parentOf(node)) // 图例22调整后进入图例21，兄弟此时变了
+					brother = tree.own(rightOf(parentOf(node))) // 图例22调整后进入图例21，兄弟此时变了
 				}
 
 				// 兄弟的右儿子是红色，进入图例21，将兄弟设置为父亲的颜色，兄弟的右儿子以及父亲变黑，对父亲左旋
-				setColor(brother, parentOf(node).color)
-				setColor(parentOf(node), BLACK)
-				setColor(rightOf(brother), BLACK)
+				tree.setColor(brother, parentOf(node).color)
+				tree.setColor(parentOf(node), BLACK)
+				tree.setColor(tree.own(rightOf(brother)), BLACK)
 				tree.rotateLeft(parentOf(node))
 
 				node = tree.root
@@ -451,34 +619,34 @@ func (tree *rbTree) fixAfterDeletion(node *rbTNode) {
 		} else {
 			// 要删除的节点在父亲右边，对应图例3，4
 			// 找出兄弟
-			brother := leftOf(parentOf(node))
+			brother := tree.own(leftOf(parentOf(node)))
 
 			// 兄弟是红色的，对应图例3，那么兄弟变黑，父亲变红，然后对父亲右旋，进入图例41,42,43
 			if isRed(brother) {
-				setColor(brother, BLACK)
-				setColor(parentOf(node), RED)
+				tree.setColor(brother, BLACK)
+				tree.setColor(parentOf(node), RED)
 				tree.rotateRight(parentOf(node))
-				brother = leftOf(parentOf(node)) // 图例3调整后进入图例41,42,43，兄弟此时变了
+				brother = tree.own(leftOf(parentOf(node))) // 图例3调整后进入图例41,42,43，兄弟此时变了
 			}
 
 			// 兄弟是黑色的，对应图例41，42，43
 			// 兄弟的左右儿子都是黑色，进入图例43，将兄弟设为红色，父亲所在的子树作为整体，当作删除的节点，继续向上递归
 			if !isRed(leftOf(brother)) && !isRed(rightOf(brother)) {
-				setColor(brother, RED)
+				tree.setColor(brother, RED)
 				node = parentOf(node)
 			} else {
 				// 兄弟的左儿子是黑色，进入图例42，将兄弟设为红色，兄弟的右儿子设为黑色，对兄弟左旋，进入图例41
 				if !isRed(leftOf(brother)) {
-					setColor(rightOf(brother), BLACK)
-					setColor(brother, RED)
+					tree.setColor(tree.own(rightOf(brother)), BLACK)
+					tree.setColor(brother, RED)
 					tree.rotateLeft(brother)
-					brother = leftOf(parentOf(node)) // 图例42调整后进入图例41，兄弟此时变了
+					brother = tree.own(leftOf(parentOf(node))) // 图例42调整后进入图例41，兄弟此时变了
 				}
 
 				// 兄弟的左儿子是红色，进入图例41，将兄弟设置为父亲的颜色，兄弟的左儿子以及父亲变黑，对父亲右旋
-				setColor(brother, parentOf(node).color)
-				setColor(parentOf(node), BLACK)
-				setColor(leftOf(brother), BLACK)
+				tree.setColor(brother, parentOf(node).color)
+				tree.setColor(parentOf(node), BLACK)
+				tree.setColor(tree.own(leftOf(brother)), BLACK)
 				tree.rotateRight(parentOf(node))
 
 				node = tree.root
@@ -487,14 +655,14 @@ func (tree *rbTree) fixAfterDeletion(node *rbTNode) {
 	}
 
 	// this node always black
-	setColor(node, BLACK)
+	tree.setColor(node, BLACK)
 }
 
 // MinKey find min key pairs
 func (tree *rbTree) MinKey() (key string, value interface{}, exist bool) {
 	// add lock
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
 	if tree.root == nil {
 		// 如果是空树，返回空
 		return
@@ -517,8 +685,8 @@ func (node *rbTNode) minNode() *rbTNode {
 // MaxKey find max key pairs
 func (tree *rbTree) MaxKey() (key string, value interface{}, exist bool) {
 	// add lock
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
 	if tree.root == nil {
 		// 如果是空树，返回空
 		return
@@ -538,10 +706,175 @@ func (node *rbTNode) maxNode() *rbTNode {
 	return node.right.maxNode()
 }
 
+// Floor find the greatest key pairs less than or equal to key
+func (tree *rbTree) Floor(key string) (floorKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.floor(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Ceiling find the least key pairs greater than or equal to key
+func (tree *rbTree) Ceiling(key string) (ceilingKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Lower find the greatest key pairs strictly less than key
+func (tree *rbTree) Lower(key string) (lowerKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.floor(key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Higher find the least key pairs strictly greater than key
+func (tree *rbTree) Higher(key string) (higherKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// 查找小于（inclusive 时小于等于）key 的最大节点
+func (tree *rbTree) floor(key string, inclusive bool) *rbTNode {
+	var candidate *rbTNode
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp > 0 {
+			// 该节点比 key 小，是候选者，继续往右子树找更大的
+			candidate = node
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// 查找大于（inclusive 时大于等于）key 的最小节点
+func (tree *rbTree) ceiling(key string, inclusive bool) *rbTNode {
+	var candidate *rbTNode
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp < 0 {
+			// 该节点比 key 大，是候选者，继续往左子树找更小的
+			candidate = node
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// Rank num of keys strictly less than key
+func (tree *rbTree) Rank(key string) int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.rank(key)
+}
+
+// 严格小于 key 的键数量，不加锁，调用者需要先加锁
+func (tree *rbTree) rank(key string) int64 {
+	var rank, n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp > 0 {
+			// 左子树和该节点都比 key 小
+			rank += node.left.treeSize() + 1
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return rank
+}
+
+// Select find the i-th smallest key pairs, i start from 0
+func (tree *rbTree) Select(i int64) (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if i < 0 || i >= tree.root.treeSize() {
+		return
+	}
+
+	node := tree.root
+	for node != nil {
+		leftSize := node.left.treeSize()
+		if i < leftSize {
+			node = node.left
+		} else if i == leftSize {
+			return node.k, node.v, true
+		} else {
+			// 跳过左子树和该节点，往右子树找
+			i = i - leftSize - 1
+			node = node.right
+		}
+	}
+
+	return
+}
+
 // Get 查找指定节点
 func (tree *rbTree) Get(key string) (value interface{}, exist bool) {
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
+
+	return tree.get(key)
+}
+
+// 查找键的值，不加锁，调用者需要先加锁
+func (tree *rbTree) get(key string) (value interface{}, exist bool) {
 	if tree.root == nil {
 		return
 	}
@@ -556,8 +889,9 @@ func (tree *rbTree) Get(key string) (value interface{}, exist bool) {
 
 // Contains 查找指定节点
 func (tree *rbTree) Contains(key string) (exist bool) {
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
 	if tree.root == nil {
 		return false
 	}
@@ -570,114 +904,58 @@ func (tree *rbTree) Contains(key string) (exist bool) {
 }
 
 func (tree *rbTree) Len() int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
 	return tree.len
 }
 
 func (tree *rbTree) GetInt(key string) (value int, exist bool, err error) {
-	var v interface{}
-	v, exist = tree.Get(key)
-	if !exist {
-		return
-	}
-
-	value, ok := v.(int)
-	if !ok {
-		err = ReflectError(v)
-		return
-	}
-
-	return value, true, nil
+	return getInt(tree.Get, key)
 }
 
 func (tree *rbTree) GetInt64(key string) (value int64, exist bool, err error) {
-	var v interface{}
-	v, exist = tree.Get(key)
-	if !exist {
-		return
-	}
-
-	value, ok := v.(int64)
-	if !ok {
-		err = ReflectError(v)
-		return
-	}
-
-	return value, true, nil
+	return getInt64(tree.Get, key)
 }
 
 func (tree *rbTree) GetString(key string) (value string, exist bool, err error) {
-	var v interface{}
-	v, exist = tree.Get(key)
-	if !exist {
-		return
-	}
-
-	value, ok := v.(string)
-	if !ok {
-		err = ReflectError(v)
-		return
-	}
-
-	return value, true, nil
+	return getString(tree.Get, key)
 }
 
 func (tree *rbTree) GetFloat64(key string) (value float64, exist bool, err error) {
-	var v interface{}
-	v, exist = tree.Get(key)
-	if !exist {
-		return
-	}
-
-	value, ok := v.(float64)
-	if !ok {
-		err = ReflectError(v)
-		return
-	}
-
-	return value, true, nil
+	return getFloat64(tree.Get, key)
 }
 
 func (tree *rbTree) GetBytes(key string) (value []byte, exist bool, err error) {
-	var v interface{}
-	v, exist = tree.Get(key)
-	if !exist {
-		return
-	}
-
-	value, ok := v.([]byte)
-	if !ok {
-		err = ReflectError(v)
-		return
-	}
-
-	return value, true, nil
+	return getBytes(tree.Get, key)
 }
 
 // find key in tree
 func (tree *rbTree) find(key string) *rbTNode {
+	var n int64
 	node := tree.root
-	for {
+	for node != nil {
+		n++
 		cmp := tree.c(key, node.k)
 		if cmp == 0 {
-			return node
+			break
 		} else if cmp < 0 {
 			node = node.left
 		} else {
 			node = node.right
 		}
-
-		if node == nil {
-			return nil
-		}
 	}
+
+	tree.stats.compare(key, n)
+	return node
 }
 
 // KeySortedList 中序遍历
 // mid order get key list
 func (tree *rbTree) KeySortedList() []string {
 	// add lock
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
 	keyList := make([]string, 0, tree.len)
 	return tree.root.midOrder(keyList)
 }
@@ -698,6 +976,28 @@ func (node *rbTNode) midOrder(keyList []string) []string {
 	return keyList
 }
 
+// KeySortedListDesc 逆中序遍历
+// reverse mid order get key list
+func (tree *rbTree) KeySortedListDesc() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+	keyList := make([]string, 0, tree.len)
+	return tree.root.midOrderDesc(keyList)
+}
+
+func (node *rbTNode) midOrderDesc(keyList []string) []string {
+	if node == nil {
+		return keyList
+	}
+
+	// 先右子树，再自己，最后左子树
+	keyList = node.right.midOrderDesc(keyList)
+	keyList = append(keyList, node.k)
+	keyList = node.left.midOrderDesc(keyList)
+
+	return keyList
+}
+
 // Check 验证是不是棵红黑树
 func (tree *rbTree) Check() bool {
 	if tree == nil || tree.root == nil {
@@ -731,9 +1031,34 @@ func (tree *rbTree) Check() bool {
 		fmt.Println("is not Balanced")
 		return false
 	}
+
+	// 判断子树节点数量和高度是否正确
+	if !tree.root.isSized() {
+		fmt.Println("is not sized")
+		return false
+	}
 	return true
 }
 
+// 节点所在的子树，记录的节点数量和高度是否正确
+func (node *rbTNode) isSized() bool {
+	if node == nil {
+		return true
+	}
+
+	if node.size != node.left.treeSize()+node.right.treeSize()+1 {
+		fmt.Printf("size %d != %d+%d+1\n", node.size, node.left.treeSize(), node.right.treeSize())
+		return false
+	}
+
+	if node.height != nodeHeight(node.left.treeHeight(), node.right.treeHeight()) {
+		fmt.Printf("height %d, left %d, right %d\n", node.height, node.left.treeHeight(), node.right.treeHeight())
+		return false
+	}
+
+	return node.left.isSized() && node.right.isSized()
+}
+
 // 节点所在的子树是否是一棵二分查找树
 func (node *rbTNode) isBST(c comparator) bool {
 	if node == nil {
@@ -857,15 +1182,15 @@ func (node *rbTNode) values() (key string, value interface{}) {
 }
 
 func (tree *rbTree) KeyList() []string {
-	tree.Lock()
-	defer tree.Unlock()
+	tree.RLock()
+	defer tree.RUnlock()
 
 	if tree.root == nil {
 		return []string{}
 	}
 
 	keyList := make([]string, 0, tree.len)
-	iterator := tree.Iterator()
+	iterator := tree.iterator()
 	for iterator.HasNext() {
 		k, _ := iterator.Next()
 		keyList = append(keyList, k)
@@ -876,9 +1201,352 @@ func (tree *rbTree) KeyList() []string {
 }
 
 func (tree *rbTree) Iterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.iterator()
+}
+
+// 层序迭代器，不加锁
+func (tree *rbTree) iterator() MapIterator {
 	q := new(linkQueue)
+	q.bind(&tree.modCount)
 	if tree.root != nil {
 		q.add(tree.root)
 	}
 	return q
+}
+
+// SafeIterator 并发写入时也安全的有序迭代器，快照模式一致，弱一致模式每步重新查找下一个键
+func (tree *rbTree) SafeIterator(mode IteratorMode) MapIterator {
+	return newSafeIterator(tree, mode)
+}
+
+// AscendIterator iterator sorted by key, from min to max
+func (tree *rbTree) AscendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := new(linkStack)
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// DescendIterator iterator sorted by key, from max to min
+func (tree *rbTree) DescendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := &linkStack{desc: true}
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// Range iterator key between from and to, sorted by key
+func (tree *rbTree) Range(from, to string, opt RangeOption) MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.rangeIterator(from, to, opt)
+}
+
+// 范围迭代器，不加锁
+func (tree *rbTree) rangeIterator(from, to string, opt RangeOption) MapIterator {
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newRangeIterator(root, tree.c, from, to, opt)
+	it.bind(&tree.modCount)
+	return it
+}
+
+// Cursor bidirectional cursor, before min key at first
+func (tree *rbTree) Cursor() Cursor {
+	return newCursor(tree)
+}
+
+// PrefixIterator iterator key with prefix, sorted by key
+func (tree *rbTree) PrefixIterator(prefix string) (MapIterator, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return it, nil
+}
+
+// KeysWithPrefix key with prefix out to list sorted
+func (tree *rbTree) KeysWithPrefix(prefix string) ([]string, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keyList := make([]string, 0)
+	for it.HasNext() {
+		k, _ := it.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList, nil
+}
+
+func (tree *rbTree) prefixIterator(prefix string) (*rangeIterator, error) {
+	if !isDefaultComparator(tree.c) {
+		return nil, ErrPrefixComparator
+	}
+
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newPrefixIterator(root, tree.c, prefix)
+	it.bind(&tree.modCount)
+	return it, nil
+}
+
+// DeleteRange delete keys which from <= key <= to, return num of deleted keys
+func (tree *rbTree) DeleteRange(from, to string) int64 {
+	return deleteRange(tree, from, to)
+}
+
+// PopMin find min key pairs and delete it
+func (tree *rbTree) PopMin() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	key = tree.root.minNode().k
+	value, exist = tree.deleteKey(key)
+	return
+}
+
+// PopMax find max key pairs and delete it
+func (tree *rbTree) PopMax() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	key = tree.root.maxNode().k
+	value, exist = tree.deleteKey(key)
+	return
+}
+
+// Ascend walk all key pairs from min to max, stop when fn return false
+func (tree *rbTree) Ascend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, nil, fn)
+	}
+}
+
+// Descend walk all key pairs from max to min, stop when fn return false
+func (tree *rbTree) Descend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		descend(tree.root, fn)
+	}
+}
+
+// AscendGreaterOrEqual walk key pairs which pivot <= key, stop when fn return false
+func (tree *rbTree) AscendGreaterOrEqual(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &pivot, nil, fn)
+	}
+}
+
+// AscendLessThan walk key pairs which key < pivot, stop when fn return false
+func (tree *rbTree) AscendLessThan(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, &pivot, fn)
+	}
+}
+
+// AscendRange walk key pairs which greaterOrEqual <= key < lessThan, stop when fn return false
+func (tree *rbTree) AscendRange(greaterOrEqual, lessThan string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &greaterOrEqual, &lessThan, fn)
+	}
+}
+
+// Snapshot read only view of tree now, cost O(1)
+// all nodes now are shared with snapshot, later write copy the path rather than change them
+func (tree *rbTree) Snapshot() ReadOnlyMap {
+	tree.Lock()
+	defer tree.Unlock()
+
+	return readOnlyMap{tree.snapshot()}
+}
+
+// snapshot without lock, caller should lock first
+func (tree *rbTree) snapshot() *rbTree {
+	tree.gen++
+	return &rbTree{
+		c:    tree.c,
+		root: tree.root,
+		len:  tree.len,
+	}
+}
+
+// 节点被快照共享时，复制一份给当前树修改，它的父亲也要先复制，也就是路径复制
+// 共享节点的 parent 只归当前树使用，快照不会读 parent，所以可以直接改
+func (tree *rbTree) own(node *rbTNode) *rbTNode {
+	if node == nil || node.gen == tree.gen {
+		return node
+	}
+
+	n := new(rbTNode)
+	*n = *node
+	n.gen = tree.gen
+
+	if node.parent == nil {
+		tree.root = n
+	} else {
+		p := tree.own(node.parent)
+		if p.left == node {
+			p.left = n
+		} else {
+			p.right = n
+		}
+
+		n.parent = p
+	}
+
+	if n.left != nil {
+		n.left.parent = n
+	}
+
+	if n.right != nil {
+		n.right.parent = n
+	}
+
+	return n
+}
+
+// Begin 开始事务，提交时在一次加锁内写入所有修改
+func (tree *rbTree) Begin() Txn {
+	return beginTxn(tree)
+}
+
+// Compute 只查找一次，用 fn 的返回值更新键值对，keep 为 false 时删除
+func (tree *rbTree) Compute(key string, fn ComputeFunc) (value interface{}, exist bool) {
+	return tree.computeOp(key, fn.op())
+}
+
+// 加锁后一次查找完成读改写，fn 可以不修改键
+func (tree *rbTree) computeOp(key string, fn computeOpFunc) (value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	return tree.compute(key, fn)
+}
+
+// 一次查找完成读改写，不加锁，调用者需要先加锁
+// fn 不修改键时不写入，没有事件也不计数，快照共享的节点不会被复制
+func (tree *rbTree) compute(key string, fn computeOpFunc) (value interface{}, exist bool) {
+	var parent *rbTNode
+	var cmp, n int64
+	t := tree.root
+	for t != nil {
+		n++
+		cmp = tree.c(key, t.k)
+		if cmp == 0 {
+			break
+		}
+
+		parent = t
+		if cmp < 0 {
+			t = t.left
+		} else {
+			t = t.right
+		}
+	}
+	tree.stats.compare(key, n)
+
+	// 键不存在，找到了插入的位置
+	if t == nil {
+		value, op := fn(nil, false)
+		if op != computePut {
+			return nil, false
+		}
+
+		tree.insert(parent, cmp, key, value)
+		return value, true
+	}
+
+	value, op := fn(t.v, true)
+	switch op {
+	case computePut:
+		tree.update(t, value)
+		return value, true
+	case computeDelete:
+		tree.deleteNode(t)
+		return nil, false
+	}
+
+	return t.v, true
+}
+
+// PutIfAbsent put if key not exist, otherwise return the exist value
+func (tree *rbTree) PutIfAbsent(key string, value interface{}) (actual interface{}, loaded bool) {
+	return putIfAbsent(tree.computeOp, key, value)
+}
+
+// Replace put only if key exist, return the old value
+func (tree *rbTree) Replace(key string, value interface{}) (old interface{}, replaced bool) {
+	return replace(tree.computeOp, key, value)
+}
+
+// CompareAndSwap put new only if value of key == old
+func (tree *rbTree) CompareAndSwap(key string, old, new interface{}) (swapped bool) {
+	return compareAndSwap(tree.computeOp, key, old, new)
+}
+
+// CompareAndDelete delete only if value of key == old
+func (tree *rbTree) CompareAndDelete(key string, old interface{}) (deleted bool) {
+	return compareAndDelete(tree.computeOp, key, old)
+}
+
+// Watch 监听键的修改，写入时发送事件，ctx 结束后关闭 channel
+func (tree *rbTree) Watch(ctx context.Context, key string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, key, false, opt)
+}
+
+// WatchPrefix 监听前缀相同的所有键的修改
+func (tree *rbTree) WatchPrefix(ctx context.Context, prefix string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, prefix, true, opt)
 }
\ No newline at end of file
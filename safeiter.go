@@ -0,0 +1,72 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+// IteratorMode how safe iterator see writes of other goroutines
+type IteratorMode int
+
+const (
+	// IteratorSnapshot iterate a snapshot taken when create, consistent, see no later write
+	// tree node is never changed after snapshot, so walking it without lock is safe
+	IteratorSnapshot IteratorMode = iota
+
+	// IteratorWeak only remember the last key, every step find the next key from map under lock, cost O(logN)
+	// keys exist during the whole iteration are seen once in order, keys put or delete during it may or may not be seen
+	// value is the one when HasNext find it
+	IteratorWeak
+)
+
+// safe iterator of map from min key to max key, never fail and never crash under concurrent write
+func newSafeIterator(m Map, mode IteratorMode) MapIterator {
+	if mode == IteratorWeak {
+		return &weakIterator{m: m}
+	}
+
+	return m.Snapshot().AscendIterator()
+}
+
+// weakly consistent iterator, implement by nearest key lookup of map
+type weakIterator struct {
+	m       Map    // map of iterator
+	started bool   // already return a key
+	key     string // last key returned
+
+	// next key pairs found by HasNext
+	ok        bool
+	nextKey   string
+	nextValue interface{}
+}
+
+func (it *weakIterator) HasNext() bool {
+	if it.ok {
+		return true
+	}
+
+	if it.started {
+		it.nextKey, it.nextValue, it.ok = it.m.Higher(it.key)
+	} else {
+		it.nextKey, it.nextValue, it.ok = it.m.MinKey()
+	}
+
+	return it.ok
+}
+
+func (it *weakIterator) Next() (key string, value interface{}) {
+	// panic here
+	if !it.HasNext() {
+		panic("Next() empty")
+	}
+
+	it.ok = false
+	it.started = true
+	it.key = it.nextKey
+	return it.nextKey, it.nextValue
+}
+
+// Err find key from map every step, never fail
+func (it *weakIterator) Err() error {
+	return nil
+}
@@ -0,0 +1,1019 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// default alpha of scapegoat tree
+const scapegoatAlpha = 0.7
+
+// Scapegoat Tree, node keep no color, balance factor or height, only size of sub tree
+// new node too deep, the nearest ancestor out of alpha weight balance is scapegoat, rebuild its sub tree perfectly balanced
+// len less than alpha*maxSize after delete rebuild the whole tree, no rotation at all
+type scapegoatTree struct {
+	modCount     int64          // num of add or delete key, iterator use it to fail fast
+	c            comparator     // tree key compare
+	root         *scapegoatNode // tree root
+	len          int64          // tree key pairs num
+	maxSize      int64          // max len since last whole tree rebuild
+	alpha        float64        // size of child <= alpha*size of node after rebuild, in (0.5, 1)
+	watch        *watchHub      // subscribers of change, nil until first watch
+	stats        mapStats       // live counters
+	sync.RWMutex                // lock for concurrent safe, read lock for lookup
+}
+
+type scapegoatNode struct {
+	k     string      // key
+	v     interface{} // value
+	left  *scapegoatNode
+	right *scapegoatNode
+	size  int64 // key pairs num of the sub tree
+}
+
+// NewScapegoatMap new a scapegoat tree map, alpha in (0.5, 1), otherwise use 0.7
+// small alpha keep tree low but rebuild more often
+func NewScapegoatMap(alpha float64) Map {
+	if !(alpha > 0.5 && alpha < 1) {
+		alpha = scapegoatAlpha
+	}
+
+	t := new(scapegoatTree)
+	t.c = comparatorDefault
+	t.alpha = alpha
+	return t
+}
+
+// cal height, walk all nodes of sub tree
+func (node *scapegoatNode) height() int64 {
+	if node == nil {
+		return 0
+	}
+
+	lh := node.left.height()
+	rh := node.right.height()
+	if lh > rh {
+		return lh + 1
+	}
+
+	return rh + 1
+}
+
+// cal sub tree size
+func (node *scapegoatNode) treeSize() int64 {
+	if node == nil {
+		return 0
+	}
+
+	return node.size
+}
+
+// Height height of tree, walk all nodes, O(N)
+func (tree *scapegoatTree) Height() int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.root.height()
+}
+
+// Stats live counters, rebalances is num of sub tree rebuild
+func (tree *scapegoatTree) Stats() Stats {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := tree.stats.load()
+	s.Len = tree.len
+	s.Height = tree.root.height()
+	s.HeightBound = scapegoatHeightBound(tree.maxSize, tree.alpha)
+	return s
+}
+
+// max depth of node, root depth is 0, floor of log_{1/alpha}(maxSize)
+func (tree *scapegoatTree) maxDepth() int64 {
+	if tree.maxSize <= 1 {
+		return 0
+	}
+
+	return int64(math.Log(float64(tree.maxSize)) / math.Log(1/tree.alpha))
+}
+
+// find key, return the node and ancestors of it from root, nil node when not found
+// cmp is compare of key and the last ancestor, key should insert to left of it when cmp less than 0
+func (tree *scapegoatTree) findPath(key string, path []*scapegoatNode) (node *scapegoatNode, cmp int64, _ []*scapegoatNode) {
+	var n int64
+	node = tree.root
+	for node != nil {
+		n++
+		cmp = tree.c(key, node.k)
+		if cmp == 0 {
+			break
+		}
+
+		path = append(path, node)
+		if cmp < 0 {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return node, cmp, path
+}
+
+// find key in tree
+func (tree *scapegoatTree) find(key string) *scapegoatNode {
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 {
+			break
+		} else if cmp < 0 {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return node
+}
+
+// rebuild sub tree of path[i] perfectly balanced and link it to parent again, without lock
+func (tree *scapegoatTree) rebuild(path []*scapegoatNode, i int) {
+	atomic.AddInt64(&tree.stats.rebalances, 1)
+
+	old := path[i]
+	nodes := old.flatten(make([]*scapegoatNode, 0, old.size))
+	root := buildBalanced(nodes)
+
+	if i == 0 {
+		tree.root = root
+	} else if path[i-1].left == old {
+		path[i-1].left = root
+	} else {
+		path[i-1].right = root
+	}
+}
+
+// nodes of sub tree in order, no recursion, tree may be deep before rebuild
+func (node *scapegoatNode) flatten(nodes []*scapegoatNode) []*scapegoatNode {
+	var stack []*scapegoatNode
+	for node != nil || len(stack) > 0 {
+		for node != nil {
+			stack = append(stack, node)
+			node = node.left
+		}
+
+		node = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		nodes = append(nodes, node)
+		node = node.right
+	}
+
+	return nodes
+}
+
+// middle node as root, so tree of sorted nodes is perfectly balanced
+func buildBalanced(nodes []*scapegoatNode) *scapegoatNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	mid := len(nodes) / 2
+	node := nodes[mid]
+	node.left = buildBalanced(nodes[:mid])
+	node.right = buildBalanced(nodes[mid+1:])
+	node.size = int64(len(nodes))
+	return node
+}
+
+func (tree *scapegoatTree) Put(key string, value interface{}) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.put(key, value)
+}
+
+// put key pairs without lock, caller should lock first
+func (tree *scapegoatTree) put(key string, value interface{}) {
+	var buf [64]*scapegoatNode
+	node, cmp, path := tree.findPath(key, buf[:0])
+	if node != nil {
+		tree.update(node, value)
+		return
+	}
+
+	tree.insert(path, cmp, key, value)
+}
+
+// insert new node under the last node of path, rebuild scapegoat when new node too deep, without lock
+func (tree *scapegoatTree) insert(path []*scapegoatNode, cmp int64, key string, value interface{}) {
+	node := &scapegoatNode{
+		k:    key,
+		v:    value,
+		size: 1,
+	}
+
+	if len(path) == 0 {
+		tree.root = node
+	} else if cmp < 0 {
+		path[len(path)-1].left = node
+	} else {
+		path[len(path)-1].right = node
+	}
+
+	// all ancestors size add 1
+	for _, p := range path {
+		p.size++
+	}
+
+	tree.len++
+	if tree.len > tree.maxSize {
+		tree.maxSize = tree.len
+	}
+
+	// depth of new node is len of path, too deep means some ancestor out of alpha weight balance
+	if int64(len(path)) > tree.maxDepth() {
+		path = append(path, node)
+		for i := len(path) - 2; i >= 0; i-- {
+			if float64(path[i+1].size) > tree.alpha*float64(path[i].size) {
+				tree.rebuild(path, i)
+				break
+			}
+		}
+	}
+
+	atomic.AddInt64(&tree.modCount, 1)
+	atomic.AddInt64(&tree.stats.puts, 1)
+	tree.watch.put(key, value, nil, false)
+}
+
+// update value of node, shape not change, without lock
+func (tree *scapegoatTree) update(node *scapegoatNode, value interface{}) {
+	old := node.v
+	node.v = value
+	atomic.AddInt64(&tree.stats.puts, 1)
+	tree.watch.put(node.k, value, old, true)
+}
+
+func (tree *scapegoatTree) Delete(key string) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.deleteKey(key)
+}
+
+// delete key without lock, caller should lock first
+func (tree *scapegoatTree) deleteKey(key string) (value interface{}, exist bool) {
+	var buf [64]*scapegoatNode
+	node, _, path := tree.findPath(key, buf[:0])
+	if node == nil {
+		return
+	}
+
+	return tree.deleteNode(append(path, node)), true
+}
+
+// delete the last node of path, path is from root, without lock
+// node has two children take key pairs of successor, then successor is deleted instead
+// len less than alpha*maxSize rebuild the whole tree
+func (tree *scapegoatTree) deleteNode(path []*scapegoatNode) (value interface{}) {
+	node := path[len(path)-1]
+	key, value := node.k, node.v
+
+	if node.left != nil && node.right != nil {
+		for s := node.right; s != nil; s = s.left {
+			path = append(path, s)
+		}
+
+		s := path[len(path)-1]
+		node.k, node.v = s.k, s.v
+	}
+
+	// the node to remove has one child at most
+	x := path[len(path)-1]
+	child := x.left
+	if child == nil {
+		child = x.right
+	}
+
+	if len(path) == 1 {
+		tree.root = child
+	} else if p := path[len(path)-2]; p.left == x {
+		p.left = child
+	} else {
+		p.right = child
+	}
+
+	// all ancestors size sub 1
+	for _, p := range path[:len(path)-1] {
+		p.size--
+	}
+
+	tree.len--
+	if float64(tree.len) < tree.alpha*float64(tree.maxSize) {
+		if tree.root != nil {
+			tree.rebuild([]*scapegoatNode{tree.root}, 0)
+		}
+		tree.maxSize = tree.len
+	}
+
+	atomic.AddInt64(&tree.modCount, 1)
+	atomic.AddInt64(&tree.stats.deletes, 1)
+	tree.watch.delete(key, value)
+	return value
+}
+
+// MinKey find min key pairs
+func (tree *scapegoatTree) MinKey() (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	node := tree.root.minNode()
+	return node.k, node.v, true
+}
+
+func (node *scapegoatNode) minNode() *scapegoatNode {
+	for node.left != nil {
+		node = node.left
+	}
+
+	return node
+}
+
+// MaxKey find max key pairs
+func (tree *scapegoatTree) MaxKey() (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	node := tree.root.maxNode()
+	return node.k, node.v, true
+}
+
+func (node *scapegoatNode) maxNode() *scapegoatNode {
+	for node.right != nil {
+		node = node.right
+	}
+
+	return node
+}
+
+// Floor find the greatest key pairs less than or equal to key
+func (tree *scapegoatTree) Floor(key string) (floorKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.floor(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Ceiling find the least key pairs greater than or equal to key
+func (tree *scapegoatTree) Ceiling(key string) (ceilingKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Lower find the greatest key pairs strictly less than key
+func (tree *scapegoatTree) Lower(key string) (lowerKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.floor(key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Higher find the least key pairs strictly greater than key
+func (tree *scapegoatTree) Higher(key string) (higherKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// the greatest node less than key, less than or equal to key when inclusive
+func (tree *scapegoatTree) floor(key string, inclusive bool) *scapegoatNode {
+	var candidate *scapegoatNode
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp > 0 {
+			candidate = node
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// the least node greater than key, greater than or equal to key when inclusive
+func (tree *scapegoatTree) ceiling(key string, inclusive bool) *scapegoatNode {
+	var candidate *scapegoatNode
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp < 0 {
+			candidate = node
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// Rank num of keys strictly less than key
+func (tree *scapegoatTree) Rank(key string) int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	var rank, n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp > 0 {
+			rank += node.left.treeSize() + 1
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return rank
+}
+
+// Select find the i-th smallest key pairs, i start from 0
+func (tree *scapegoatTree) Select(i int64) (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if i < 0 || i >= tree.root.treeSize() {
+		return
+	}
+
+	node := tree.root
+	for node != nil {
+		leftSize := node.left.treeSize()
+		if i < leftSize {
+			node = node.left
+		} else if i == leftSize {
+			return node.k, node.v, true
+		} else {
+			i = i - leftSize - 1
+			node = node.right
+		}
+	}
+
+	return
+}
+
+// Get find value of key
+func (tree *scapegoatTree) Get(key string) (value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
+
+	return tree.get(key)
+}
+
+// get value of key without lock, caller should lock first
+func (tree *scapegoatTree) get(key string) (value interface{}, exist bool) {
+	if node := tree.find(key); node != nil {
+		return node.v, true
+	}
+
+	return
+}
+
+// Contains key exist or not
+func (tree *scapegoatTree) Contains(key string) (exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
+
+	return tree.find(key) != nil
+}
+
+func (tree *scapegoatTree) Len() int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.len
+}
+
+func (tree *scapegoatTree) GetInt(key string) (value int, exist bool, err error) {
+	return getInt(tree.Get, key)
+}
+
+func (tree *scapegoatTree) GetInt64(key string) (value int64, exist bool, err error) {
+	return getInt64(tree.Get, key)
+}
+
+func (tree *scapegoatTree) GetString(key string) (value string, exist bool, err error) {
+	return getString(tree.Get, key)
+}
+
+func (tree *scapegoatTree) GetFloat64(key string) (value float64, exist bool, err error) {
+	return getFloat64(tree.Get, key)
+}
+
+func (tree *scapegoatTree) GetBytes(key string) (value []byte, exist bool, err error) {
+	return getBytes(tree.Get, key)
+}
+
+func (tree *scapegoatTree) KeySortedList() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, nil, func(key string, value interface{}) bool {
+			keyList = append(keyList, key)
+			return true
+		})
+	}
+
+	return keyList
+}
+
+func (tree *scapegoatTree) KeySortedListDesc() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	if tree.root != nil {
+		descend(tree.root, func(key string, value interface{}) bool {
+			keyList = append(keyList, key)
+			return true
+		})
+	}
+
+	return keyList
+}
+
+// Check binary search tree by key and size is right, len and height in alpha weight bound
+func (tree *scapegoatTree) Check() bool {
+	if tree == nil || tree.root == nil {
+		return true
+	}
+
+	if tree.root.size != tree.len {
+		fmt.Printf("root size %d != len %d\n", tree.root.size, tree.len)
+		return false
+	}
+
+	if !tree.root.isBST(tree.c, nil, nil) {
+		fmt.Println("is not BST")
+		return false
+	}
+
+	// delete rebuild the whole tree before len less than alpha*maxSize
+	if float64(tree.len) < tree.alpha*float64(tree.maxSize) {
+		fmt.Printf("len %d < %f*maxSize %d\n", tree.len, tree.alpha, tree.maxSize)
+		return false
+	}
+
+	// put rebuild scapegoat before any node deeper than log_{1/alpha}(maxSize)
+	if h := tree.root.height(); h > tree.maxDepth()+1 {
+		fmt.Printf("height %d > %d\n", h, tree.maxDepth()+1)
+		return false
+	}
+
+	return true
+}
+
+// check sub tree, all keys in (lo, hi), nil means no bound
+func (node *scapegoatNode) isBST(compare comparator, lo, hi *string) bool {
+	if node == nil {
+		return true
+	}
+
+	if (lo != nil && compare(*lo, node.k) >= 0) || (hi != nil && compare(node.k, *hi) >= 0) {
+		fmt.Printf("key %s is not sorted\n", node.k)
+		return false
+	}
+
+	if node.size != node.left.treeSize()+node.right.treeSize()+1 {
+		fmt.Printf("size %d != %d+%d+1\n", node.size, node.left.treeSize(), node.right.treeSize())
+		return false
+	}
+
+	return node.left.isBST(compare, lo, &node.k) && node.right.isBST(compare, &node.k, hi)
+}
+
+func (node *scapegoatNode) leftOf() bsTreeNode {
+	if node.left == nil {
+		return nil
+	}
+
+	return node.left
+}
+
+func (node *scapegoatNode) rightOf() bsTreeNode {
+	if node.right == nil {
+		return nil
+	}
+
+	return node.right
+}
+
+// not check node nil, may be panic, user should deal by oneself
+func (node *scapegoatNode) values() (key string, value interface{}) {
+	return node.k, node.v
+}
+
+func (tree *scapegoatTree) KeyList() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	iterator := tree.iterator()
+	for iterator.HasNext() {
+		k, _ := iterator.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList
+}
+
+func (tree *scapegoatTree) Iterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.iterator()
+}
+
+// layer order iterator, without lock
+func (tree *scapegoatTree) iterator() MapIterator {
+	q := new(linkQueue)
+	q.bind(&tree.modCount)
+	if tree.root != nil {
+		q.add(tree.root)
+	}
+	return q
+}
+
+// SafeIterator sorted iterator safe under concurrent write, snapshot mode is consistent, weak mode find next key every step
+func (tree *scapegoatTree) SafeIterator(mode IteratorMode) MapIterator {
+	return newSafeIterator(tree, mode)
+}
+
+// AscendIterator iterator sorted by key, from min to max
+func (tree *scapegoatTree) AscendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := new(linkStack)
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// DescendIterator iterator sorted by key, from max to min
+func (tree *scapegoatTree) DescendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := &linkStack{desc: true}
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// Range iterator key between from and to, sorted by key
+func (tree *scapegoatTree) Range(from, to string, opt RangeOption) MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.rangeIterator(from, to, opt)
+}
+
+// range iterator without lock
+func (tree *scapegoatTree) rangeIterator(from, to string, opt RangeOption) MapIterator {
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newRangeIterator(root, tree.c, from, to, opt)
+	it.bind(&tree.modCount)
+	return it
+}
+
+// Cursor bidirectional cursor, before min key at first
+func (tree *scapegoatTree) Cursor() Cursor {
+	return newCursor(tree)
+}
+
+// PrefixIterator iterator key with prefix, sorted by key
+func (tree *scapegoatTree) PrefixIterator(prefix string) (MapIterator, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return it, nil
+}
+
+// KeysWithPrefix key with prefix out to list sorted
+func (tree *scapegoatTree) KeysWithPrefix(prefix string) ([]string, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keyList := make([]string, 0)
+	for it.HasNext() {
+		k, _ := it.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList, nil
+}
+
+func (tree *scapegoatTree) prefixIterator(prefix string) (*rangeIterator, error) {
+	if !isDefaultComparator(tree.c) {
+		return nil, ErrPrefixComparator
+	}
+
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newPrefixIterator(root, tree.c, prefix)
+	it.bind(&tree.modCount)
+	return it, nil
+}
+
+// DeleteRange delete keys which from <= key <= to, return num of deleted keys
+func (tree *scapegoatTree) DeleteRange(from, to string) int64 {
+	return deleteRange(tree, from, to)
+}
+
+// PopMin find min key pairs and delete it
+func (tree *scapegoatTree) PopMin() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	var buf [64]*scapegoatNode
+	path := buf[:0]
+	for node := tree.root; node != nil; node = node.left {
+		path = append(path, node)
+	}
+
+	key = path[len(path)-1].k
+	return key, tree.deleteNode(path), true
+}
+
+// PopMax find max key pairs and delete it
+func (tree *scapegoatTree) PopMax() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	var buf [64]*scapegoatNode
+	path := buf[:0]
+	for node := tree.root; node != nil; node = node.right {
+		path = append(path, node)
+	}
+
+	key = path[len(path)-1].k
+	return key, tree.deleteNode(path), true
+}
+
+// Ascend walk all key pairs from min to max, stop when fn return false
+func (tree *scapegoatTree) Ascend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, nil, fn)
+	}
+}
+
+// Descend walk all key pairs from max to min, stop when fn return false
+func (tree *scapegoatTree) Descend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		descend(tree.root, fn)
+	}
+}
+
+// AscendGreaterOrEqual walk key pairs which pivot <= key, stop when fn return false
+func (tree *scapegoatTree) AscendGreaterOrEqual(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &pivot, nil, fn)
+	}
+}
+
+// AscendLessThan walk key pairs which key < pivot, stop when fn return false
+func (tree *scapegoatTree) AscendLessThan(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, &pivot, fn)
+	}
+}
+
+// AscendRange walk key pairs which greaterOrEqual <= key < lessThan, stop when fn return false
+func (tree *scapegoatTree) AscendRange(greaterOrEqual, lessThan string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &greaterOrEqual, &lessThan, fn)
+	}
+}
+
+func (tree *scapegoatTree) SetComparator(c comparator) Map {
+	tree.Lock()
+	defer tree.Unlock()
+	if tree.len == 0 {
+		tree.c = c
+	}
+
+	return tree
+}
+
+// Snapshot read only view of tree now
+// node has no gen to share with snapshot, copy all nodes under read lock, writers wait, cost O(N)
+func (tree *scapegoatTree) Snapshot() ReadOnlyMap {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return readOnlyMap{&scapegoatTree{
+		c:       tree.c,
+		root:    tree.root.clone(),
+		len:     tree.len,
+		maxSize: tree.maxSize,
+		alpha:   tree.alpha,
+	}}
+}
+
+// copy whole sub tree
+func (node *scapegoatNode) clone() *scapegoatNode {
+	if node == nil {
+		return nil
+	}
+
+	n := new(scapegoatNode)
+	*n = *node
+	n.left = node.left.clone()
+	n.right = node.right.clone()
+	return n
+}
+
+// Begin transaction, all writes apply on commit under one lock
+func (tree *scapegoatTree) Begin() Txn {
+	return beginTxn(tree)
+}
+
+// Compute find key once, put value return by fn, delete key when fn return keep false
+func (tree *scapegoatTree) Compute(key string, fn ComputeFunc) (value interface{}, exist bool) {
+	return tree.computeOp(key, fn.op())
+}
+
+// compute with lock, nothing write when fn leave the key unchanged
+func (tree *scapegoatTree) computeOp(key string, fn computeOpFunc) (value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	var buf [64]*scapegoatNode
+	node, cmp, path := tree.findPath(key, buf[:0])
+
+	// key not exist, the last node of path is the place to insert
+	if node == nil {
+		value, op := fn(nil, false)
+		if op != computePut {
+			return nil, false
+		}
+
+		tree.insert(path, cmp, key, value)
+		return value, true
+	}
+
+	value, op := fn(node.v, true)
+	switch op {
+	case computePut:
+		tree.update(node, value)
+		return value, true
+	case computeDelete:
+		tree.deleteNode(append(path, node))
+		return nil, false
+	}
+
+	return node.v, true
+}
+
+// PutIfAbsent put if key not exist, otherwise return the exist value
+func (tree *scapegoatTree) PutIfAbsent(key string, value interface{}) (actual interface{}, loaded bool) {
+	return putIfAbsent(tree.computeOp, key, value)
+}
+
+// Replace put only if key exist, return the old value
+func (tree *scapegoatTree) Replace(key string, value interface{}) (old interface{}, replaced bool) {
+	return replace(tree.computeOp, key, value)
+}
+
+// CompareAndSwap put new only if value of key == old
+func (tree *scapegoatTree) CompareAndSwap(key string, old, new interface{}) (swapped bool) {
+	return compareAndSwap(tree.computeOp, key, old, new)
+}
+
+// CompareAndDelete delete only if value of key == old
+func (tree *scapegoatTree) CompareAndDelete(key string, old interface{}) (deleted bool) {
+	return compareAndDelete(tree.computeOp, key, old)
+}
+
+// Watch watch put and delete of key, channel closed after ctx done
+func (tree *scapegoatTree) Watch(ctx context.Context, key string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, key, false, opt)
+}
+
+// WatchPrefix watch put and delete of keys with prefix
+func (tree *scapegoatTree) WatchPrefix(ctx context.Context, prefix string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, prefix, true, opt)
+}
@@ -0,0 +1,848 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+)
+
+// ShardOption option of sharded map
+type ShardOption struct {
+	Hash func(key string) uint64 // hash key to choose shard, default is fnv-1a
+}
+
+// sharded map, split keys across many rbt to cut lock contention
+// single key operation only lock one shard
+// operation across shards lock all shards in order, so result is globally sorted and consistent
+type shardedMap struct {
+	shards []*rbTree               // every shard is a rbt map
+	hash   func(key string) uint64 // hash key to choose shard
+	c      comparator              // tree key compare, only change when all shards locked
+	watch  *watchHub               // subscribers of change, shared by all shards
+}
+
+// NewShardedMap new a map split keys across shards rbt map, shards < 1 will be 1
+func NewShardedMap(shards int, opt ShardOption) Map {
+	if shards < 1 {
+		shards = 1
+	}
+
+	m := &shardedMap{
+		shards: make([]*rbTree, shards),
+		hash:   opt.Hash,
+		c:      comparatorDefault,
+		watch:  newWatchHub(),
+	}
+
+	if m.hash == nil {
+		m.hash = fnvHash
+	}
+
+	for i := range m.shards {
+		t := new(rbTree)
+		t.c = comparatorDefault
+		t.watch = m.watch
+		m.shards[i] = t
+	}
+
+	return m
+}
+
+// fnv-1a hash, not alloc
+func fnvHash(key string) uint64 {
+	var h uint64 = 14695981039346656037
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+
+	return h
+}
+
+// choose shard of key
+func (m *shardedMap) shard(key string) *rbTree {
+	return m.shards[m.hash(key)%uint64(len(m.shards))]
+}
+
+// lock all shards in order, avoid dead lock
+func (m *shardedMap) lockAll() {
+	for _, tree := range m.shards {
+		tree.Lock()
+	}
+}
+
+func (m *shardedMap) unlockAll() {
+	for i := len(m.shards) - 1; i >= 0; i-- {
+		m.shards[i].Unlock()
+	}
+}
+
+func (m *shardedMap) rLockAll() {
+	for _, tree := range m.shards {
+		tree.RLock()
+	}
+}
+
+func (m *shardedMap) rUnlockAll() {
+	for i := len(m.shards) - 1; i >= 0; i-- {
+		m.shards[i].RUnlock()
+	}
+}
+
+// root of shard, nil when shard is empty
+func shardRoot(tree *rbTree) bsTreeNode {
+	if tree.root == nil {
+		return nil
+	}
+
+	return tree.root
+}
+
+func (m *shardedMap) Put(key string, value interface{}) {
+	m.shard(key).Put(key, value)
+}
+
+func (m *shardedMap) Delete(key string) {
+	m.shard(key).Delete(key)
+}
+
+func (m *shardedMap) Get(key string) (value interface{}, exist bool) {
+	return m.shard(key).Get(key)
+}
+
+// get value of key without lock, caller should lock the shard first
+func (m *shardedMap) get(key string) (value interface{}, exist bool) {
+	return m.shard(key).get(key)
+}
+
+func (m *shardedMap) GetInt(key string) (value int, exist bool, err error) {
+	return m.shard(key).GetInt(key)
+}
+
+func (m *shardedMap) GetInt64(key string) (value int64, exist bool, err error) {
+	return m.shard(key).GetInt64(key)
+}
+
+func (m *shardedMap) GetString(key string) (value string, exist bool, err error) {
+	return m.shard(key).GetString(key)
+}
+
+func (m *shardedMap) GetFloat64(key string) (value float64, exist bool, err error) {
+	return m.shard(key).GetFloat64(key)
+}
+
+func (m *shardedMap) GetBytes(key string) (value []byte, exist bool, err error) {
+	return m.shard(key).GetBytes(key)
+}
+
+func (m *shardedMap) Contains(key string) (exist bool) {
+	return m.shard(key).Contains(key)
+}
+
+// Len lock all shards, so it is consistent across shards
+func (m *shardedMap) Len() int64 {
+	m.rLockAll()
+	defer m.rUnlockAll()
+
+	var n int64
+	for _, tree := range m.shards {
+		n += tree.len
+	}
+
+	return n
+}
+
+// KeyList every shard layer order, one shard by one shard
+func (m *shardedMap) KeyList() []string {
+	keyList := make([]string, 0)
+	for _, tree := range m.shards {
+		keyList = append(keyList, tree.KeyList()...)
+	}
+
+	return keyList
+}
+
+// Iterator every shard layer order, one shard by one shard
+func (m *shardedMap) Iterator() MapIterator {
+	its := make([]MapIterator, 0, len(m.shards))
+	for _, tree := range m.shards {
+		its = append(its, tree.Iterator())
+	}
+
+	return &chainIterator{its: its}
+}
+
+func (m *shardedMap) KeySortedList() []string {
+	keyList := make([]string, 0)
+	m.walk(false, func(root bsTreeNode, c comparator) MapIterator {
+		s := new(linkStack)
+		s.pushPath(root)
+		return s
+	}, func(key string, value interface{}) bool {
+		keyList = append(keyList, key)
+		return true
+	})
+
+	return keyList
+}
+
+func (m *shardedMap) KeySortedListDesc() []string {
+	keyList := make([]string, 0)
+	m.walk(true, func(root bsTreeNode, c comparator) MapIterator {
+		s := &linkStack{desc: true}
+		s.pushPath(root)
+		return s
+	}, func(key string, value interface{}) bool {
+		keyList = append(keyList, key)
+		return true
+	})
+
+	return keyList
+}
+
+func (m *shardedMap) MaxKey() (key string, value interface{}, exist bool) {
+	m.rLockAll()
+	defer m.rUnlockAll()
+
+	var max *rbTNode
+	for _, tree := range m.shards {
+		if tree.root == nil {
+			continue
+		}
+
+		node := tree.root.maxNode()
+		if max == nil || m.c(node.k, max.k) > 0 {
+			max = node
+		}
+	}
+
+	if max == nil {
+		return
+	}
+
+	return max.k, max.v, true
+}
+
+func (m *shardedMap) MinKey() (key string, value interface{}, exist bool) {
+	m.rLockAll()
+	defer m.rUnlockAll()
+
+	var min *rbTNode
+	for _, tree := range m.shards {
+		if tree.root == nil {
+			continue
+		}
+
+		node := tree.root.minNode()
+		if min == nil || m.c(node.k, min.k) < 0 {
+			min = node
+		}
+	}
+
+	if min == nil {
+		return
+	}
+
+	return min.k, min.v, true
+}
+
+// SetComparator only work when all shards are empty
+func (m *shardedMap) SetComparator(c comparator) Map {
+	m.lockAll()
+	defer m.unlockAll()
+
+	for _, tree := range m.shards {
+		if tree.len != 0 {
+			return m
+		}
+	}
+
+	m.c = c
+	for _, tree := range m.shards {
+		tree.c = c
+	}
+
+	return m
+}
+
+// Check every shard is a rbt and every key in the right shard
+func (m *shardedMap) Check() bool {
+	for i, tree := range m.shards {
+		if !tree.Check() {
+			return false
+		}
+
+		for _, key := range tree.KeySortedList() {
+			if m.shard(key) != tree {
+				fmt.Printf("key %s should not in shard %d\n", key, i)
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Height max height of shards
+func (m *shardedMap) Height() int64 {
+	m.rLockAll()
+	defer m.rUnlockAll()
+
+	var h int64
+	for _, tree := range m.shards {
+		if th := tree.root.treeHeight(); th > h {
+			h = th
+		}
+	}
+
+	return h
+}
+
+// Stats sum counters of all shards, height and bound are the max one of shards
+func (m *shardedMap) Stats() Stats {
+	m.rLockAll()
+	defer m.rUnlockAll()
+
+	var s Stats
+	for _, tree := range m.shards {
+		s.add(tree.stat())
+	}
+
+	return s
+}
+
+// find nearest node of every shard, choose the best one
+// less is true when want the greatest node, otherwise want the least node
+func (m *shardedMap) nearest(key string, less, inclusive bool) (nearestKey string, value interface{}, exist bool) {
+	m.rLockAll()
+	defer m.rUnlockAll()
+
+	var best *rbTNode
+	for _, tree := range m.shards {
+		var node *rbTNode
+		if less {
+			node = tree.floor(key, inclusive)
+		} else {
+			node = tree.ceiling(key, inclusive)
+		}
+
+		if node == nil {
+			continue
+		}
+
+		if best == nil {
+			best = node
+			continue
+		}
+
+		cmp := m.c(node.k, best.k)
+		if (less && cmp > 0) || (!less && cmp < 0) {
+			best = node
+		}
+	}
+
+	if best == nil {
+		return
+	}
+
+	return best.k, best.v, true
+}
+
+func (m *shardedMap) Floor(key string) (floorKey string, value interface{}, exist bool) {
+	return m.nearest(key, true, true)
+}
+
+func (m *shardedMap) Ceiling(key string) (ceilingKey string, value interface{}, exist bool) {
+	return m.nearest(key, false, true)
+}
+
+func (m *shardedMap) Lower(key string) (lowerKey string, value interface{}, exist bool) {
+	return m.nearest(key, true, false)
+}
+
+func (m *shardedMap) Higher(key string) (higherKey string, value interface{}, exist bool) {
+	return m.nearest(key, false, false)
+}
+
+// Rank sum of rank in every shard
+func (m *shardedMap) Rank(key string) int64 {
+	m.rLockAll()
+	defer m.rUnlockAll()
+
+	var rank int64
+	for _, tree := range m.shards {
+		rank += tree.rank(key)
+	}
+
+	return rank
+}
+
+// Select walk the merged order of shards, cost O(i*logK), K is num of shards
+func (m *shardedMap) Select(i int64) (key string, value interface{}, exist bool) {
+	if i < 0 {
+		return
+	}
+
+	m.walk(false, func(root bsTreeNode, c comparator) MapIterator {
+		s := new(linkStack)
+		s.pushPath(root)
+		return s
+	}, func(k string, v interface{}) bool {
+		if i == 0 {
+			key, value, exist = k, v, true
+			return false
+		}
+
+		i--
+		return true
+	})
+
+	return
+}
+
+// SafeIterator snapshot mode lock all shards once, weak mode lock all shards every step
+func (m *shardedMap) SafeIterator(mode IteratorMode) MapIterator {
+	return newSafeIterator(m, mode)
+}
+
+func (m *shardedMap) AscendIterator() MapIterator {
+	return m.merge(false, func(root bsTreeNode, c comparator) MapIterator {
+		s := new(linkStack)
+		s.pushPath(root)
+		return s
+	})
+}
+
+func (m *shardedMap) DescendIterator() MapIterator {
+	return m.merge(true, func(root bsTreeNode, c comparator) MapIterator {
+		s := &linkStack{desc: true}
+		s.pushPath(root)
+		return s
+	})
+}
+
+func (m *shardedMap) Range(from, to string, opt RangeOption) MapIterator {
+	return m.merge(false, func(root bsTreeNode, c comparator) MapIterator {
+		return newRangeIterator(root, c, from, to, opt)
+	})
+}
+
+func (m *shardedMap) Cursor() Cursor {
+	return newCursor(m)
+}
+
+func (m *shardedMap) PrefixIterator(prefix string) (MapIterator, error) {
+	if err := m.checkPrefixComparator(); err != nil {
+		return nil, err
+	}
+
+	return m.merge(false, func(root bsTreeNode, c comparator) MapIterator {
+		return newPrefixIterator(root, c, prefix)
+	}), nil
+}
+
+func (m *shardedMap) KeysWithPrefix(prefix string) ([]string, error) {
+	if err := m.checkPrefixComparator(); err != nil {
+		return nil, err
+	}
+
+	keyList := make([]string, 0)
+	m.walk(false, func(root bsTreeNode, c comparator) MapIterator {
+		return newPrefixIterator(root, c, prefix)
+	}, func(key string, value interface{}) bool {
+		keyList = append(keyList, key)
+		return true
+	})
+
+	return keyList, nil
+}
+
+func (m *shardedMap) checkPrefixComparator() error {
+	m.shards[0].RLock()
+	defer m.shards[0].RUnlock()
+
+	if !isDefaultComparator(m.c) {
+		return ErrPrefixComparator
+	}
+
+	return nil
+}
+
+// DeleteRange lock all shards, delete keys which from <= key <= to
+func (m *shardedMap) DeleteRange(from, to string) int64 {
+	m.lockAll()
+	defer m.unlockAll()
+
+	var n int64
+	for _, tree := range m.shards {
+		n += deleteRangeKeys(tree, from, to)
+	}
+
+	return n
+}
+
+// pop the min or max key pairs of all shards, lock all shards so it is atomic
+func (m *shardedMap) pop(max bool) (key string, value interface{}, exist bool) {
+	m.lockAll()
+	defer m.unlockAll()
+
+	var best *rbTree
+	for _, tree := range m.shards {
+		if tree.root == nil {
+			continue
+		}
+
+		if best == nil {
+			best = tree
+			continue
+		}
+
+		if max && m.c(tree.root.maxNode().k, best.root.maxNode().k) > 0 {
+			best = tree
+		} else if !max && m.c(tree.root.minNode().k, best.root.minNode().k) < 0 {
+			best = tree
+		}
+	}
+
+	if best == nil {
+		return
+	}
+
+	if max {
+		key = best.root.maxNode().k
+	} else {
+		key = best.root.minNode().k
+	}
+
+	value, exist = best.deleteKey(key)
+	return
+}
+
+func (m *shardedMap) PopMin() (key string, value interface{}, exist bool) {
+	return m.pop(false)
+}
+
+func (m *shardedMap) PopMax() (key string, value interface{}, exist bool) {
+	return m.pop(true)
+}
+
+func (m *shardedMap) Ascend(fn WalkFunc) {
+	m.walk(false, func(root bsTreeNode, c comparator) MapIterator {
+		s := new(linkStack)
+		s.pushPath(root)
+		return s
+	}, fn)
+}
+
+func (m *shardedMap) Descend(fn WalkFunc) {
+	m.walk(true, func(root bsTreeNode, c comparator) MapIterator {
+		s := &linkStack{desc: true}
+		s.pushPath(root)
+		return s
+	}, fn)
+}
+
+func (m *shardedMap) AscendGreaterOrEqual(pivot string, fn WalkFunc) {
+	m.walk(false, func(root bsTreeNode, c comparator) MapIterator {
+		return newRangeIterator(root, c, pivot, "", RangeOption{ToUnbounded: true})
+	}, fn)
+}
+
+func (m *shardedMap) AscendLessThan(pivot string, fn WalkFunc) {
+	m.walk(false, func(root bsTreeNode, c comparator) MapIterator {
+		return newRangeIterator(root, c, "", pivot, RangeOption{FromUnbounded: true, ToExclusive: true})
+	}, fn)
+}
+
+func (m *shardedMap) AscendRange(greaterOrEqual, lessThan string, fn WalkFunc) {
+	m.walk(false, func(root bsTreeNode, c comparator) MapIterator {
+		return newRangeIterator(root, c, greaterOrEqual, lessThan, RangeOption{ToExclusive: true})
+	}, fn)
+}
+
+// Snapshot lock all shards and snapshot every shard, so it is consistent across shards, cost O(shards)
+func (m *shardedMap) Snapshot() ReadOnlyMap {
+	m.lockAll()
+	defer m.unlockAll()
+
+	snap := &shardedMap{
+		shards: make([]*rbTree, len(m.shards)),
+		hash:   m.hash,
+		c:      m.c,
+	}
+
+	for i, tree := range m.shards {
+		snap.shards[i] = tree.snapshot()
+	}
+
+	return readOnlyMap{snap}
+}
+
+// Begin transaction, commit lock all shards, so it is atomic across shards
+func (m *shardedMap) Begin() Txn {
+	return newTxn(m, m.commit)
+}
+
+// check keys read and apply writes of transaction with all shards locked
+func (m *shardedMap) commit(reads map[string]txnRead, writes map[string]txnWrite) error {
+	m.lockAll()
+	defer m.unlockAll()
+
+	if err := checkReads(m.get, reads); err != nil {
+		return err
+	}
+
+	for key, w := range writes {
+		if w.deleted {
+			m.shard(key).deleteKey(key)
+		} else {
+			m.shard(key).put(key, w.value)
+		}
+	}
+
+	return nil
+}
+
+// Compute only lock the shard of key
+func (m *shardedMap) Compute(key string, fn ComputeFunc) (value interface{}, exist bool) {
+	return m.shard(key).computeOp(key, fn.op())
+}
+
+// compute on the shard of key, fn may leave the key unchanged
+func (m *shardedMap) computeOp(key string, fn computeOpFunc) (value interface{}, exist bool) {
+	return m.shard(key).computeOp(key, fn)
+}
+
+// PutIfAbsent put if key not exist, otherwise return the exist value
+func (m *shardedMap) PutIfAbsent(key string, value interface{}) (actual interface{}, loaded bool) {
+	return putIfAbsent(m.computeOp, key, value)
+}
+
+// Replace put only if key exist, return the old value
+func (m *shardedMap) Replace(key string, value interface{}) (old interface{}, replaced bool) {
+	return replace(m.computeOp, key, value)
+}
+
+// CompareAndSwap put new only if value of key == old
+func (m *shardedMap) CompareAndSwap(key string, old, new interface{}) (swapped bool) {
+	return compareAndSwap(m.computeOp, key, old, new)
+}
+
+// CompareAndDelete delete only if value of key == old
+func (m *shardedMap) CompareAndDelete(key string, old interface{}) (deleted bool) {
+	return compareAndDelete(m.computeOp, key, old)
+}
+
+// build iterator of every shard with all shards read locked, merge them to one sorted iterator
+// merged iterator fail fast when any shard modified
+func (m *shardedMap) merge(desc bool, build func(root bsTreeNode, c comparator) MapIterator) MapIterator {
+	m.rLockAll()
+	defer m.rUnlockAll()
+
+	its := make([]MapIterator, 0, len(m.shards))
+	guards := make([]modGuard, len(m.shards))
+	for i, tree := range m.shards {
+		its = append(its, build(shardRoot(tree), m.c))
+		guards[i].bind(&tree.modCount)
+	}
+
+	it := newMergeIterator(its, m.c, desc)
+	it.guards = guards
+	return it
+}
+
+// walk merged iterator with all shards read locked, stop when fn return false
+func (m *shardedMap) walk(desc bool, build func(root bsTreeNode, c comparator) MapIterator, fn WalkFunc) {
+	m.rLockAll()
+	defer m.rUnlockAll()
+
+	its := make([]MapIterator, 0, len(m.shards))
+	for _, tree := range m.shards {
+		its = append(its, build(shardRoot(tree), m.c))
+	}
+
+	it := newMergeIterator(its, m.c, desc)
+	for it.HasNext() {
+		if !fn(it.Next()) {
+			return
+		}
+	}
+}
+
+// iterator one by one
+type chainIterator struct {
+	its []MapIterator // iterators
+	i   int           // current iterator
+}
+
+func (it *chainIterator) HasNext() bool {
+	for ; it.i < len(it.its); it.i++ {
+		if it.its[it.i].HasNext() {
+			return true
+		}
+
+		if it.its[it.i].Err() != nil {
+			return false
+		}
+	}
+
+	return false
+}
+
+func (it *chainIterator) Next() (key string, value interface{}) {
+	if !it.HasNext() {
+		if it.Err() != nil {
+			return
+		}
+
+		panic("Next() empty")
+	}
+
+	return it.its[it.i].Next()
+}
+
+func (it *chainIterator) Err() error {
+	for _, i := range it.its {
+		if err := i.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// head key pairs of every sorted iterator
+type mergeItem struct {
+	key   string
+	value interface{}
+	it    MapIterator
+}
+
+// min heap of head key pairs, max heap when desc
+type mergeHeap struct {
+	items []*mergeItem
+	c     comparator
+	desc  bool
+}
+
+func (h *mergeHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *mergeHeap) Less(i, j int) bool {
+	cmp := h.c(h.items[i].key, h.items[j].key)
+	if h.desc {
+		return cmp > 0
+	}
+
+	return cmp < 0
+}
+
+func (h *mergeHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *mergeHeap) Push(x interface{}) {
+	h.items = append(h.items, x.(*mergeItem))
+}
+
+func (h *mergeHeap) Pop() interface{} {
+	n := len(h.items)
+	x := h.items[n-1]
+	h.items = h.items[:n-1]
+	return x
+}
+
+// k-way merge sorted iterators to one sorted iterator
+type mergeIterator struct {
+	h      *mergeHeap    // head key pairs of every iterator
+	its    []MapIterator // all iterators, for checking Err
+	guards []modGuard    // fail fast when any shard modified
+	err    error         // first err of iterators
+}
+
+func newMergeIterator(its []MapIterator, c comparator, desc bool) *mergeIterator {
+	h := &mergeHeap{
+		items: make([]*mergeItem, 0, len(its)),
+		c:     c,
+		desc:  desc,
+	}
+
+	for _, it := range its {
+		if it.HasNext() {
+			k, v := it.Next()
+			h.items = append(h.items, &mergeItem{key: k, value: v, it: it})
+		}
+	}
+
+	heap.Init(h)
+	return &mergeIterator{h: h, its: its}
+}
+
+// HasNext stop when any iterator fail
+func (it *mergeIterator) HasNext() bool {
+	if it.Err() != nil {
+		return false
+	}
+
+	return it.h.Len() > 0
+}
+
+func (it *mergeIterator) Next() (key string, value interface{}) {
+	if !it.HasNext() {
+		if it.err != nil {
+			return
+		}
+
+		panic("Next() empty")
+	}
+
+	// 堆顶就是最小的，取出后从同一个迭代器补充下一个
+	top := it.h.items[0]
+	key, value = top.key, top.value
+	if top.it.HasNext() {
+		top.key, top.value = top.it.Next()
+		heap.Fix(it.h, 0)
+	} else {
+		heap.Pop(it.h)
+	}
+
+	return key, value
+}
+
+func (it *mergeIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+
+	for i := range it.guards {
+		if !it.guards[i].check() {
+			it.err = it.guards[i].Err()
+			return it.err
+		}
+	}
+
+	for _, i := range it.its {
+		if err := i.Err(); err != nil {
+			it.err = err
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Watch watch put and delete of key, all shards send to the same subscribers
+func (m *shardedMap) Watch(ctx context.Context, key string, opt WatchOption) <-chan WatchEvent {
+	return m.watch.watch(ctx, key, false, opt)
+}
+
+// WatchPrefix watch put and delete of keys with prefix, keys of prefix are across all shards
+func (m *shardedMap) WatchPrefix(ctx context.Context, prefix string, opt WatchOption) <-chan WatchEvent {
+	return m.watch.watch(ctx, prefix, true, opt)
+}
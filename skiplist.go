@@ -0,0 +1,946 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// max level of skip list, enough for 2^32 key pairs
+const skipListMaxLevel = 32
+
+// next pointer with delete mark, never change, cas the whole pointer to change it
+type markRef struct {
+	node   *slNode // next node
+	marked bool    // owner node is deleted, can not link new node after it
+}
+
+// value of node, change by cas the whole pointer
+type slValue struct {
+	v interface{}
+}
+
+// skip list node
+type slNode struct {
+	k     string           // key, never change
+	value unsafe.Pointer   // *slValue, nil means node is deleted
+	next  []unsafe.Pointer // *markRef of every level
+}
+
+func (node *slNode) loadNext(level int) *markRef {
+	return (*markRef)(atomic.LoadPointer(&node.next[level]))
+}
+
+// cas next of level from unmarked expect to unmarked next
+func (node *slNode) casNext(level int, expect, next *slNode) bool {
+	old := node.loadNext(level)
+	if old.node != expect || old.marked {
+		return false
+	}
+
+	return atomic.CompareAndSwapPointer(&node.next[level], unsafe.Pointer(old), unsafe.Pointer(&markRef{node: next}))
+}
+
+func (node *slNode) loadValue() *slValue {
+	return (*slValue)(atomic.LoadPointer(&node.value))
+}
+
+func (node *slNode) casValue(old, new *slValue) bool {
+	return atomic.CompareAndSwapPointer(&node.value, unsafe.Pointer(old), unsafe.Pointer(new))
+}
+
+// mark next of every level from top to bottom, then no node can link after it
+func (node *slNode) markAll() {
+	for level := len(node.next) - 1; level >= 0; level-- {
+		for {
+			old := node.loadNext(level)
+			if old.marked {
+				break
+			}
+
+			if atomic.CompareAndSwapPointer(&node.next[level], unsafe.Pointer(old), unsafe.Pointer(&markRef{node: old.node, marked: true})) {
+				break
+			}
+		}
+	}
+}
+
+// lock free skip list, refer Java ConcurrentSkipListMap
+// delete a key in three steps: cas value to nil, mark next of every level, unlink it when find
+// iterator is weakly consistent, never fail and never block writers
+// readers never take a lock, writers share the read lock of writeMu and still run at the same time by cas
+// snapshot hold the write lock of writeMu to stop all writers, so it is point in time
+type skipListMap struct {
+	head    *slNode      // head of every level, has no key
+	len     int64        // key pairs num, change by atomic
+	level   int32        // levels in use, change by atomic
+	seed    uint64       // seed of random level, change by atomic
+	c       atomic.Value // tree key compare
+	watch   *watchHub    // subscribers of change
+	stats   mapStats     // live counters
+	writeMu sync.RWMutex // writers hold read lock, snapshot hold write lock
+}
+
+// NewSkipListMap new a lock free skip list map, it is not a drop-in replacement of tree maps:
+// readers never take a lock, so they may see part of a transaction before Commit return
+// writers of the same key on different goroutines may send watch events out of order
+func NewSkipListMap() Map {
+	m := &skipListMap{
+		head:  newSlNode("", nil, skipListMaxLevel),
+		level: 1,
+		watch: newWatchHub(),
+	}
+
+	m.c.Store(comparator(comparatorDefault))
+	return m
+}
+
+func newSlNode(key string, value *slValue, level int) *slNode {
+	node := &slNode{
+		k:     key,
+		value: unsafe.Pointer(value),
+		next:  make([]unsafe.Pointer, level),
+	}
+
+	for i := range node.next {
+		node.next[i] = unsafe.Pointer(&markRef{})
+	}
+
+	return node
+}
+
+func (m *skipListMap) comparator() comparator {
+	return m.c.Load().(comparator)
+}
+
+// random level of new node, level i with probability 1/2^i, splitmix64 so no lock
+func (m *skipListMap) randomLevel() int {
+	x := atomic.AddUint64(&m.seed, 0x9E3779B97F4A7C15)
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	x ^= x >> 31
+
+	level := bits.TrailingZeros64(x) + 1
+	if level > skipListMaxLevel {
+		level = skipListMaxLevel
+	}
+
+	return level
+}
+
+// raise levels in use
+func (m *skipListMap) raiseLevel(level int) {
+	for {
+		old := atomic.LoadInt32(&m.level)
+		if int32(level) <= old || atomic.CompareAndSwapInt32(&m.level, old, int32(level)) {
+			return
+		}
+	}
+}
+
+// find the last node less than key and the node after it on every level, unlink marked nodes on the way
+// return true if the node after it on bottom level has the key
+func (m *skipListMap) find(c comparator, key string, preds, succs *[skipListMaxLevel]*slNode) bool {
+	top := int(atomic.LoadInt32(&m.level))
+	for level := top; level < skipListMaxLevel; level++ {
+		preds[level] = m.head
+		succs[level] = nil
+	}
+
+	var n int64
+	defer func() {
+		m.stats.compare(key, n)
+	}()
+
+retry:
+	for {
+		pred := m.head
+		for level := top - 1; level >= 0; level-- {
+			curr := pred.loadNext(level).node
+			for curr != nil {
+				next := curr.loadNext(level)
+
+				// curr is deleted, unlink it, start again if pred changed
+				if next.marked {
+					if !pred.casNext(level, curr, next.node) {
+						continue retry
+					}
+
+					curr = next.node
+					continue
+				}
+
+				n++
+				if c(curr.k, key) >= 0 {
+					break
+				}
+
+				pred = curr
+				curr = next.node
+			}
+
+			preds[level] = pred
+			succs[level] = curr
+		}
+
+		n++
+		return succs[0] != nil && c(succs[0].k, key) == 0
+	}
+}
+
+// last node on bottom level which before return true, skip marked nodes, head if not found
+// not unlink marked nodes, so never write
+func (m *skipListMap) last(before func(k string) bool) *slNode {
+	pred := m.head
+	for level := int(atomic.LoadInt32(&m.level)) - 1; level >= 0; level-- {
+		curr := pred.loadNext(level).node
+		for curr != nil {
+			next := curr.loadNext(level)
+			if next.marked {
+				curr = next.node
+				continue
+			}
+
+			if !before(curr.k) {
+				break
+			}
+
+			pred = curr
+			curr = next.node
+		}
+	}
+
+	return pred
+}
+
+// first not deleted node after node on bottom level
+func (m *skipListMap) nextAlive(node *slNode) (*slNode, *slValue) {
+	for node = node.loadNext(0).node; node != nil; node = node.loadNext(0).node {
+		if v := node.loadValue(); v != nil {
+			return node, v
+		}
+	}
+
+	return nil, nil
+}
+
+// the least not deleted node greater than or equal to key, greater than key when not inclusive
+func (m *skipListMap) ceiling(c comparator, key string, inclusive bool) (*slNode, *slValue) {
+	var n int64
+	node, v := m.nextAlive(m.last(func(k string) bool {
+		n++
+		cmp := c(k, key)
+		return cmp < 0 || (cmp == 0 && !inclusive)
+	}))
+
+	m.stats.compare(key, n)
+	return node, v
+}
+
+// the greatest not deleted node less than or equal to key, less than key when not inclusive
+func (m *skipListMap) floor(c comparator, key string, inclusive bool) (*slNode, *slValue) {
+	var n int64
+	defer func() {
+		m.stats.compare(key, n)
+	}()
+
+	for {
+		node := m.last(func(k string) bool {
+			n++
+			cmp := c(k, key)
+			return cmp < 0 || (cmp == 0 && inclusive)
+		})
+
+		if node == m.head {
+			return nil, nil
+		}
+
+		// deleted after found, help mark it then find again
+		if v := node.loadValue(); v != nil {
+			return node, v
+		}
+
+		node.markAll()
+	}
+}
+
+// the greatest not deleted node
+func (m *skipListMap) maxNode() (*slNode, *slValue) {
+	for {
+		node := m.last(func(k string) bool {
+			return true
+		})
+
+		if node == m.head {
+			return nil, nil
+		}
+
+		if v := node.loadValue(); v != nil {
+			return node, v
+		}
+
+		node.markAll()
+	}
+}
+
+// node is deleted by cas value to nil, mark and unlink it
+func (m *skipListMap) unlink(c comparator, node *slNode) {
+	node.markAll()
+
+	var preds, succs [skipListMaxLevel]*slNode
+	m.find(c, node.k, &preds, &succs)
+	atomic.AddInt64(&m.len, -1)
+}
+
+// link new node after preds, return false if preds changed, caller should find again
+func (m *skipListMap) insert(c comparator, key string, value interface{}, preds, succs *[skipListMaxLevel]*slNode) bool {
+	level := m.randomLevel()
+	node := &slNode{
+		k:     key,
+		value: unsafe.Pointer(&slValue{v: value}),
+		next:  make([]unsafe.Pointer, level),
+	}
+
+	for i := 0; i < level; i++ {
+		node.next[i] = unsafe.Pointer(&markRef{node: succs[i]})
+	}
+
+	// link on bottom level, key is added now
+	if !preds[0].casNext(0, succs[0], node) {
+		return false
+	}
+
+	atomic.AddInt64(&m.len, 1)
+	m.raiseLevel(level)
+
+	// link upper levels
+	for i := 1; i < level; i++ {
+		for {
+			next := node.loadNext(i)
+
+			// deleted by other goroutine, stop linking
+			if next.marked {
+				return true
+			}
+
+			if next.node != succs[i] && !atomic.CompareAndSwapPointer(&node.next[i], unsafe.Pointer(next), unsafe.Pointer(&markRef{node: succs[i]})) {
+				continue
+			}
+
+			if preds[i].casNext(i, succs[i], node) {
+				break
+			}
+
+			if !m.find(c, key, preds, succs) || succs[0] != node {
+				return true
+			}
+		}
+	}
+
+	return true
+}
+
+func (m *skipListMap) Put(key string, value interface{}) {
+	m.computeOp(key, func(old interface{}, exists bool) (interface{}, computeOp) {
+		return value, computePut
+	})
+}
+
+func (m *skipListMap) Delete(key string) {
+	m.computeOp(key, func(old interface{}, exists bool) (interface{}, computeOp) {
+		return nil, computeDelete
+	})
+}
+
+// delete key, return the deleted value
+func (m *skipListMap) remove(key string) (value interface{}, exist bool) {
+	m.computeOp(key, func(old interface{}, exists bool) (interface{}, computeOp) {
+		value, exist = old, exists
+		return nil, computeDelete
+	})
+
+	return
+}
+
+// Compute cas loop, fn may be called again when other goroutine change the key first
+func (m *skipListMap) Compute(key string, fn ComputeFunc) (value interface{}, exist bool) {
+	return m.computeOp(key, fn.op())
+}
+
+// cas loop with read lock of writeMu, nothing write when fn leave the key unchanged
+func (m *skipListMap) computeOp(key string, fn computeOpFunc) (value interface{}, exist bool) {
+	m.writeMu.RLock()
+	defer m.writeMu.RUnlock()
+
+	return m.compute(key, fn)
+}
+
+// cas loop, caller should hold writeMu
+func (m *skipListMap) compute(key string, fn computeOpFunc) (value interface{}, exist bool) {
+	c := m.comparator()
+	var preds, succs [skipListMaxLevel]*slNode
+	for {
+		if !m.find(c, key, &preds, &succs) {
+			value, op := fn(nil, false)
+			if op != computePut {
+				return nil, false
+			}
+
+			if m.insert(c, key, value, &preds, &succs) {
+				atomic.AddInt64(&m.stats.puts, 1)
+				m.watch.put(key, value, nil, false)
+				return value, true
+			}
+
+			continue
+		}
+
+		node := succs[0]
+		old := node.loadValue()
+
+		// deleting by other goroutine, help it then find again
+		if old == nil {
+			node.markAll()
+			continue
+		}
+
+		value, op := fn(old.v, true)
+		switch op {
+		case computePut:
+			if node.casValue(old, &slValue{v: value}) {
+				atomic.AddInt64(&m.stats.puts, 1)
+				m.watch.put(key, value, old.v, true)
+				return value, true
+			}
+		case computeDelete:
+			if node.casValue(old, nil) {
+				m.unlink(c, node)
+				atomic.AddInt64(&m.stats.deletes, 1)
+				m.watch.delete(key, old.v)
+				return nil, false
+			}
+		default:
+			return old.v, true
+		}
+	}
+}
+
+func (m *skipListMap) PutIfAbsent(key string, value interface{}) (actual interface{}, loaded bool) {
+	return putIfAbsent(m.computeOp, key, value)
+}
+
+func (m *skipListMap) Replace(key string, value interface{}) (old interface{}, replaced bool) {
+	return replace(m.computeOp, key, value)
+}
+
+func (m *skipListMap) CompareAndSwap(key string, old, new interface{}) (swapped bool) {
+	return compareAndSwap(m.computeOp, key, old, new)
+}
+
+func (m *skipListMap) CompareAndDelete(key string, old interface{}) (deleted bool) {
+	return compareAndDelete(m.computeOp, key, old)
+}
+
+func (m *skipListMap) Get(key string) (value interface{}, exist bool) {
+	m.stats.get(key)
+	return m.get(key)
+}
+
+// get value of key, not count it
+func (m *skipListMap) get(key string) (value interface{}, exist bool) {
+	c := m.comparator()
+	node, v := m.ceiling(c, key, true)
+	if node == nil || c(node.k, key) != 0 {
+		return nil, false
+	}
+
+	return v.v, true
+}
+
+func (m *skipListMap) GetInt(key string) (value int, exist bool, err error) {
+	return getInt(m.Get, key)
+}
+
+func (m *skipListMap) GetInt64(key string) (value int64, exist bool, err error) {
+	return getInt64(m.Get, key)
+}
+
+func (m *skipListMap) GetString(key string) (value string, exist bool, err error) {
+	return getString(m.Get, key)
+}
+
+func (m *skipListMap) GetFloat64(key string) (value float64, exist bool, err error) {
+	return getFloat64(m.Get, key)
+}
+
+func (m *skipListMap) GetBytes(key string) (value []byte, exist bool, err error) {
+	return getBytes(m.Get, key)
+}
+
+func (m *skipListMap) Contains(key string) (exist bool) {
+	_, exist = m.Get(key)
+	return
+}
+
+func (m *skipListMap) Len() int64 {
+	return atomic.LoadInt64(&m.len)
+}
+
+// KeyList skip list has no layer order, same as KeySortedList
+func (m *skipListMap) KeyList() []string {
+	return m.KeySortedList()
+}
+
+// Iterator skip list has no layer order, same as AscendIterator
+func (m *skipListMap) Iterator() MapIterator {
+	return m.AscendIterator()
+}
+
+func (m *skipListMap) KeySortedList() []string {
+	keyList := make([]string, 0)
+	m.Ascend(func(key string, value interface{}) bool {
+		keyList = append(keyList, key)
+		return true
+	})
+
+	return keyList
+}
+
+func (m *skipListMap) KeySortedListDesc() []string {
+	keyList := m.KeySortedList()
+	for i, j := 0, len(keyList)-1; i < j; i, j = i+1, j-1 {
+		keyList[i], keyList[j] = keyList[j], keyList[i]
+	}
+
+	return keyList
+}
+
+func (m *skipListMap) MaxKey() (key string, value interface{}, exist bool) {
+	node, v := m.maxNode()
+	if node == nil {
+		return
+	}
+
+	return node.k, v.v, true
+}
+
+func (m *skipListMap) MinKey() (key string, value interface{}, exist bool) {
+	node, v := m.nextAlive(m.head)
+	if node == nil {
+		return
+	}
+
+	return node.k, v.v, true
+}
+
+// SetComparator only work when map is empty
+func (m *skipListMap) SetComparator(c comparator) Map {
+	if m.Len() == 0 {
+		m.c.Store(c)
+	}
+
+	return m
+}
+
+// Check every level sorted, every node on level i has level i, call it when no writer
+func (m *skipListMap) Check() bool {
+	c := m.comparator()
+	for level := 0; level < skipListMaxLevel; level++ {
+		var prev *slNode
+		for node := m.head.loadNext(level).node; node != nil; node = node.loadNext(level).node {
+			if len(node.next) <= level {
+				fmt.Printf("node %s has %d levels but on level %d\n", node.k, len(node.next), level)
+				return false
+			}
+
+			if prev != nil && c(prev.k, node.k) >= 0 {
+				fmt.Printf("level %d is not sorted, %s before %s\n", level, prev.k, node.k)
+				return false
+			}
+
+			prev = node
+		}
+	}
+
+	var n int64
+	m.Ascend(func(key string, value interface{}) bool {
+		n++
+		return true
+	})
+
+	if n != m.Len() {
+		fmt.Printf("len %d but has %d key pairs\n", m.Len(), n)
+		return false
+	}
+
+	return true
+}
+
+// Height levels in use
+func (m *skipListMap) Height() int64 {
+	return int64(atomic.LoadInt32(&m.level))
+}
+
+// Stats skip list has no rotation and no hard height bound, expected levels is log(Len)
+func (m *skipListMap) Stats() Stats {
+	s := m.stats.load()
+	s.Len = m.Len()
+	s.Height = m.Height()
+	return s
+}
+
+func (m *skipListMap) Floor(key string) (floorKey string, value interface{}, exist bool) {
+	node, v := m.floor(m.comparator(), key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, v.v, true
+}
+
+func (m *skipListMap) Ceiling(key string) (ceilingKey string, value interface{}, exist bool) {
+	node, v := m.ceiling(m.comparator(), key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, v.v, true
+}
+
+func (m *skipListMap) Lower(key string) (lowerKey string, value interface{}, exist bool) {
+	node, v := m.floor(m.comparator(), key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, v.v, true
+}
+
+func (m *skipListMap) Higher(key string) (higherKey string, value interface{}, exist bool) {
+	node, v := m.ceiling(m.comparator(), key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, v.v, true
+}
+
+// Rank skip list has no sub tree size, count on bottom level, cost O(N)
+func (m *skipListMap) Rank(key string) int64 {
+	var rank int64
+	m.AscendLessThan(key, func(key string, value interface{}) bool {
+		rank++
+		return true
+	})
+
+	return rank
+}
+
+// Select skip list has no sub tree size, walk on bottom level, cost O(N)
+func (m *skipListMap) Select(i int64) (key string, value interface{}, exist bool) {
+	if i < 0 {
+		return
+	}
+
+	m.Ascend(func(k string, v interface{}) bool {
+		if i == 0 {
+			key, value, exist = k, v, true
+			return false
+		}
+
+		i--
+		return true
+	})
+
+	return
+}
+
+// SafeIterator snapshot mode copy key pairs first and writers wait until the copy is done
+func (m *skipListMap) SafeIterator(mode IteratorMode) MapIterator {
+	return newSafeIterator(m, mode)
+}
+
+func (m *skipListMap) AscendIterator() MapIterator {
+	node, v := m.nextAlive(m.head)
+	return &skipListIterator{m: m, node: node, value: v}
+}
+
+func (m *skipListMap) DescendIterator() MapIterator {
+	node, v := m.maxNode()
+	return &skipListIterator{m: m, node: node, value: v, desc: true}
+}
+
+func (m *skipListMap) Range(from, to string, opt RangeOption) MapIterator {
+	c := m.comparator()
+	it := &skipListIterator{m: m}
+	if !opt.ToUnbounded {
+		it.within = func(key string) bool {
+			cmp := c(key, to)
+			return cmp < 0 || (cmp == 0 && !opt.ToExclusive)
+		}
+	}
+
+	if opt.FromUnbounded {
+		it.node, it.value = m.nextAlive(m.head)
+	} else {
+		it.node, it.value = m.ceiling(c, from, !opt.FromExclusive)
+	}
+
+	return it
+}
+
+func (m *skipListMap) Cursor() Cursor {
+	return newCursor(m)
+}
+
+func (m *skipListMap) PrefixIterator(prefix string) (MapIterator, error) {
+	c := m.comparator()
+	if !isDefaultComparator(c) {
+		return nil, ErrPrefixComparator
+	}
+
+	node, v := m.ceiling(c, prefix, true)
+	return &skipListIterator{
+		m:     m,
+		node:  node,
+		value: v,
+		within: func(key string) bool {
+			return strings.HasPrefix(key, prefix)
+		},
+	}, nil
+}
+
+func (m *skipListMap) KeysWithPrefix(prefix string) ([]string, error) {
+	it, err := m.PrefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keyList := make([]string, 0)
+	for it.HasNext() {
+		k, _ := it.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList, nil
+}
+
+// DeleteRange delete key one by one, not atomic in skip list
+func (m *skipListMap) DeleteRange(from, to string) int64 {
+	keyList := make([]string, 0)
+	it := m.Range(from, to, RangeOption{})
+	for it.HasNext() {
+		k, _ := it.Next()
+		keyList = append(keyList, k)
+	}
+
+	var n int64
+	for _, k := range keyList {
+		if _, exist := m.remove(k); exist {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (m *skipListMap) PopMin() (key string, value interface{}, exist bool) {
+	m.writeMu.RLock()
+	defer m.writeMu.RUnlock()
+
+	for {
+		node, v := m.nextAlive(m.head)
+		if node == nil {
+			return
+		}
+
+		// who cas value to nil own the key
+		if node.casValue(v, nil) {
+			m.unlink(m.comparator(), node)
+			atomic.AddInt64(&m.stats.deletes, 1)
+			m.watch.delete(node.k, v.v)
+			return node.k, v.v, true
+		}
+	}
+}
+
+func (m *skipListMap) PopMax() (key string, value interface{}, exist bool) {
+	m.writeMu.RLock()
+	defer m.writeMu.RUnlock()
+
+	for {
+		node, v := m.maxNode()
+		if node == nil {
+			return
+		}
+
+		if node.casValue(v, nil) {
+			m.unlink(m.comparator(), node)
+			atomic.AddInt64(&m.stats.deletes, 1)
+			m.watch.delete(node.k, v.v)
+			return node.k, v.v, true
+		}
+	}
+}
+
+// Ascend not hold any lock, see key pairs weakly consistent
+func (m *skipListMap) Ascend(fn WalkFunc) {
+	for node, v := m.nextAlive(m.head); node != nil; node, v = m.nextAlive(node) {
+		if !fn(node.k, v.v) {
+			return
+		}
+	}
+}
+
+// Descend find the lower key every step, cost O(logN) every step
+func (m *skipListMap) Descend(fn WalkFunc) {
+	c := m.comparator()
+	for node, v := m.maxNode(); node != nil; node, v = m.floor(c, node.k, false) {
+		if !fn(node.k, v.v) {
+			return
+		}
+	}
+}
+
+func (m *skipListMap) AscendGreaterOrEqual(pivot string, fn WalkFunc) {
+	for node, v := m.ceiling(m.comparator(), pivot, true); node != nil; node, v = m.nextAlive(node) {
+		if !fn(node.k, v.v) {
+			return
+		}
+	}
+}
+
+func (m *skipListMap) AscendLessThan(pivot string, fn WalkFunc) {
+	c := m.comparator()
+	for node, v := m.nextAlive(m.head); node != nil && c(node.k, pivot) < 0; node, v = m.nextAlive(node) {
+		if !fn(node.k, v.v) {
+			return
+		}
+	}
+}
+
+func (m *skipListMap) AscendRange(greaterOrEqual, lessThan string, fn WalkFunc) {
+	c := m.comparator()
+	for node, v := m.ceiling(c, greaterOrEqual, true); node != nil && c(node.k, lessThan) < 0; node, v = m.nextAlive(node) {
+		if !fn(node.k, v.v) {
+			return
+		}
+	}
+}
+
+// Snapshot lock free skip list can not share nodes, copy key pairs, cost O(N)
+// writers wait until the copy is done, so it is point in time, readers never wait
+func (m *skipListMap) Snapshot() ReadOnlyMap {
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+
+	snap := &skipListMap{
+		head:  newSlNode("", nil, skipListMaxLevel),
+		level: 1,
+	}
+	snap.c.Store(m.comparator())
+
+	// keys come sorted, always link at the end of every level
+	var tails [skipListMaxLevel]*slNode
+	for i := range tails {
+		tails[i] = snap.head
+	}
+
+	m.Ascend(func(key string, value interface{}) bool {
+		level := snap.randomLevel()
+		node := newSlNode(key, &slValue{v: value}, level)
+		for i := 0; i < level; i++ {
+			tails[i].next[i] = unsafe.Pointer(&markRef{node: node})
+			tails[i] = node
+		}
+
+		snap.raiseLevel(level)
+		snap.len++
+		return true
+	})
+
+	return readOnlyMap{snap}
+}
+
+// Begin transaction, commit hold the write lock of writeMu, so other writers never run in the middle of it
+// readers never take a lock, they may see part of the writes before Commit return
+func (m *skipListMap) Begin() Txn {
+	return newTxn(m, m.commit)
+}
+
+// check keys read and apply writes of transaction with all writers stopped
+func (m *skipListMap) commit(reads map[string]txnRead, writes map[string]txnWrite) error {
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+
+	if err := checkReads(m.get, reads); err != nil {
+		return err
+	}
+
+	for key, w := range writes {
+		w := w
+		m.compute(key, func(old interface{}, exists bool) (interface{}, computeOp) {
+			if w.deleted {
+				return nil, computeDelete
+			}
+
+			return w.value, computePut
+		})
+	}
+
+	return nil
+}
+
+// weakly consistent iterator of skip list, never fail
+// see key pairs at some time between create and next, may not see keys put after create
+type skipListIterator struct {
+	m      *skipListMap
+	node   *slNode               // next node
+	value  *slValue              // value of next node when found
+	desc   bool                  // from max to min
+	within func(key string) bool // key still not reach the upper bound, nil means no upper bound
+}
+
+func (it *skipListIterator) HasNext() bool {
+	return it.node != nil && (it.within == nil || it.within(it.node.k))
+}
+
+func (it *skipListIterator) Next() (key string, value interface{}) {
+	// panic here
+	if !it.HasNext() {
+		panic("Next() empty")
+	}
+
+	key, value = it.node.k, it.value.v
+	if it.desc {
+		it.node, it.value = it.m.floor(it.m.comparator(), key, false)
+	} else {
+		it.node, it.value = it.m.nextAlive(it.node)
+	}
+
+	return key, value
+}
+
+// Err weakly consistent iterator never fail
+func (it *skipListIterator) Err() error {
+	return nil
+}
+
+// Watch watch put and delete of key, writers on different goroutines may send events of same key out of order
+func (m *skipListMap) Watch(ctx context.Context, key string, opt WatchOption) <-chan WatchEvent {
+	return m.watch.watch(ctx, key, false, opt)
+}
+
+// WatchPrefix watch put and delete of keys with prefix, writers on different goroutines may send events of same key out of order
+func (m *skipListMap) WatchPrefix(ctx context.Context, prefix string, opt WatchOption) <-chan WatchEvent {
+	return m.watch.watch(ctx, prefix, true, opt)
+}
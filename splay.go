@@ -0,0 +1,1037 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Splay Tree, every Get, Put and Delete move the key to root by rotation, hot keys stay near root
+// Get change the tree, so it take the write lock, nearest key lookup, rank and walk not splay and take the read lock
+// no hard height bound, cost O(logN) amortized
+type splayTree struct {
+	modCount     int64      // num of add or delete key, iterator use it to fail fast
+	c            comparator // tree key compare
+	root         *splayNode // tree root
+	len          int64      // tree key pairs num
+	gen          uint64     // node gen less than it is shared by snapshot or iterator, copy before change
+	readOnly     bool       // tree is a snapshot, never splay
+	watch        *watchHub  // subscribers of change, nil until first watch
+	stats        mapStats   // live counters
+	sync.RWMutex            // lock for concurrent safe, read lock for lookup which not splay
+}
+
+type splayNode struct {
+	k      string      // key
+	v      interface{} // value
+	left   *splayNode
+	right  *splayNode
+	height int32  // height of the sub tree, only for Height and Stats
+	size   int64  // key pairs num of the sub tree
+	gen    uint64 // gen of tree when node created or copied
+}
+
+// NewSplayMap new a splay tree map, good for skewed access such as zipf
+func NewSplayMap() Map {
+	t := new(splayTree)
+	t.c = comparatorDefault
+	return t
+}
+
+// height of sub tree, nil is 0
+func (node *splayNode) treeHeight() int64 {
+	if node == nil {
+		return 0
+	}
+
+	return int64(node.height)
+}
+
+// cal sub tree size
+func (node *splayNode) treeSize() int64 {
+	if node == nil {
+		return 0
+	}
+
+	return node.size
+}
+
+// Height root keep height of the whole tree, O(1)
+func (tree *splayTree) Height() int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.root.treeHeight()
+}
+
+// Stats live counters, splay tree has no hard height bound
+func (tree *splayTree) Stats() Stats {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := tree.stats.load()
+	s.Len = tree.len
+	s.Height = tree.root.treeHeight()
+	return s
+}
+
+// node shared, copy it for tree to change, caller should link the copy to parent
+func (tree *splayTree) own(node *splayNode) *splayNode {
+	if node == nil || node.gen == tree.gen {
+		return node
+	}
+
+	n := new(splayNode)
+	*n = *node
+	n.gen = tree.gen
+	return n
+}
+
+// copy shared nodes of path from root, and link them again, so it is path copying
+func (tree *splayTree) ownPath(path []*splayNode) {
+	for i, node := range path {
+		n := tree.own(node)
+		if n == node {
+			continue
+		}
+
+		path[i] = n
+		if i == 0 {
+			tree.root = n
+		} else if path[i-1].left == node {
+			path[i-1].left = n
+		} else {
+			path[i-1].right = n
+		}
+	}
+}
+
+// nodes now are shared, iterator walk them without seeing later splay
+func (tree *splayTree) share() {
+	tree.gen++
+}
+
+// find key in tree, no splay, without lock
+func (tree *splayTree) find(key string) *splayNode {
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 {
+			break
+		} else if cmp < 0 {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return node
+}
+
+// find key, return the node and ancestors of it from root, nil node when not found
+// cmp is compare of key and the last ancestor, key should insert to left of it when cmp less than 0
+func (tree *splayTree) findPath(key string, path []*splayNode) (node *splayNode, cmp int64, _ []*splayNode) {
+	var n int64
+	node = tree.root
+	for node != nil {
+		n++
+		cmp = tree.c(key, node.k)
+		if cmp == 0 {
+			break
+		}
+
+		path = append(path, node)
+		if cmp < 0 {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return node, cmp, path
+}
+
+// child rise over parent, grand is parent of parent, nil when parent is root, all should be owned
+// return 1 when right rotate, 0 when left rotate
+func (tree *splayTree) rotateUp(grand, parent, child *splayNode) (right int64) {
+	if parent.left == child {
+		parent.left = child.right
+		child.right = parent
+		right = 1
+	} else {
+		parent.right = child.left
+		child.left = parent
+	}
+
+	// child take place of parent, so size and height of parent change
+	child.size = parent.size
+	parent.size = parent.left.treeSize() + parent.right.treeSize() + 1
+	parent.height = nodeHeight(parent.left.treeHeight(), parent.right.treeHeight())
+	child.height = nodeHeight(child.left.treeHeight(), child.right.treeHeight())
+
+	if grand == nil {
+		tree.root = child
+	} else if grand.left == parent {
+		grand.left = child
+	} else {
+		grand.right = child
+	}
+
+	return
+}
+
+// move the last node of path to root, path should be owned
+func (tree *splayTree) splay(path []*splayNode) {
+	if len(path) < 2 {
+		return
+	}
+
+	// count rotations once, atomic add every rotation is slow
+	var rotations, right int64
+	defer func() {
+		atomic.AddInt64(&tree.stats.rightRotations, right)
+		atomic.AddInt64(&tree.stats.leftRotations, rotations-right)
+	}()
+
+	x := path[len(path)-1]
+	for i := len(path) - 1; i > 0; i -= 2 {
+		p := path[i-1]
+		if i == 1 {
+			// zig
+			right += tree.rotateUp(nil, p, x)
+			rotations++
+			return
+		}
+
+		var gg *splayNode
+		g := path[i-2]
+		if i >= 3 {
+			gg = path[i-3]
+		}
+
+		if (g.left == p) == (p.left == x) {
+			// zig-zig, rotate parent first
+			right += tree.rotateUp(gg, g, p)
+			right += tree.rotateUp(gg, p, x)
+		} else {
+			// zig-zag
+			right += tree.rotateUp(g, p, x)
+			right += tree.rotateUp(gg, g, x)
+		}
+		rotations += 2
+	}
+}
+
+// copy path and splay the last node of it, without lock
+func (tree *splayTree) splayPath(path []*splayNode) {
+	if len(path) == 0 {
+		return
+	}
+
+	tree.ownPath(path)
+	tree.splay(path)
+}
+
+// find key and splay it, splay the last node on the way when not found, snapshot only find, without lock
+func (tree *splayTree) access(key string) *splayNode {
+	if tree.readOnly {
+		return tree.find(key)
+	}
+
+	var buf [64]*splayNode
+	node, _, path := tree.findPath(key, buf[:0])
+	if node != nil {
+		path = append(path, node)
+	}
+
+	tree.splayPath(path)
+	return node
+}
+
+func (tree *splayTree) Put(key string, value interface{}) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.put(key, value)
+}
+
+// put key pairs without lock, caller should lock first
+func (tree *splayTree) put(key string, value interface{}) {
+	var buf [64]*splayNode
+	node, cmp, path := tree.findPath(key, buf[:0])
+	if node != nil {
+		tree.update(append(path, node), value)
+		return
+	}
+
+	tree.insert(path, cmp, key, value)
+}
+
+// insert new node under the last node of path, then splay it to root, without lock
+func (tree *splayTree) insert(path []*splayNode, cmp int64, key string, value interface{}) {
+	tree.ownPath(path)
+	node := &splayNode{
+		k:      key,
+		v:      value,
+		height: 1,
+		size:   1,
+		gen:    tree.gen,
+	}
+
+	if len(path) == 0 {
+		tree.root = node
+	} else if cmp < 0 {
+		path[len(path)-1].left = node
+	} else {
+		path[len(path)-1].right = node
+	}
+
+	// all ancestors size add 1, all of them rotate down by splay, so height is fixed there
+	for _, p := range path {
+		p.size++
+	}
+	tree.splay(append(path, node))
+
+	tree.len++
+	atomic.AddInt64(&tree.modCount, 1)
+	atomic.AddInt64(&tree.stats.puts, 1)
+	tree.watch.put(key, value, nil, false)
+}
+
+// update value of the last node of path and splay it to root, without lock
+func (tree *splayTree) update(path []*splayNode, value interface{}) {
+	tree.splayPath(path)
+	old := tree.root.v
+	tree.root.v = value
+	atomic.AddInt64(&tree.stats.puts, 1)
+	tree.watch.put(tree.root.k, value, old, true)
+}
+
+func (tree *splayTree) Delete(key string) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.deleteKey(key)
+}
+
+// delete key without lock, caller should lock first
+func (tree *splayTree) deleteKey(key string) (value interface{}, exist bool) {
+	var buf [64]*splayNode
+	node, _, path := tree.findPath(key, buf[:0])
+	if node == nil {
+		// splay the last node on the way even if key not found
+		tree.splayPath(path)
+		return
+	}
+
+	return tree.deleteNode(append(path, node)), true
+}
+
+// splay the last node of path to root, then join left and right sub tree of it, without lock
+func (tree *splayTree) deleteNode(path []*splayNode) (value interface{}) {
+	tree.splayPath(path)
+	node := tree.root
+	key, value := node.k, node.v
+
+	left, right := node.left, node.right
+	if left == nil {
+		tree.root = right
+	} else {
+		// splay max key of left sub tree to be root, it has no right child, hang right sub tree on it
+		tree.root = left
+		path = path[:0]
+		for n := left; n != nil; n = n.right {
+			path = append(path, n)
+		}
+		tree.splayPath(path)
+
+		tree.root.right = right
+		tree.root.size += right.treeSize()
+		tree.root.height = nodeHeight(tree.root.left.treeHeight(), right.treeHeight())
+	}
+
+	tree.len--
+	atomic.AddInt64(&tree.modCount, 1)
+	atomic.AddInt64(&tree.stats.deletes, 1)
+	tree.watch.delete(key, value)
+	return value
+}
+
+// MinKey find min key pairs, not splay
+func (tree *splayTree) MinKey() (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	node := tree.root.minNode()
+	return node.k, node.v, true
+}
+
+func (node *splayNode) minNode() *splayNode {
+	for node.left != nil {
+		node = node.left
+	}
+
+	return node
+}
+
+// MaxKey find max key pairs, not splay
+func (tree *splayTree) MaxKey() (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	node := tree.root.maxNode()
+	return node.k, node.v, true
+}
+
+func (node *splayNode) maxNode() *splayNode {
+	for node.right != nil {
+		node = node.right
+	}
+
+	return node
+}
+
+// Floor find the greatest key pairs less than or equal to key
+func (tree *splayTree) Floor(key string) (floorKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.floor(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Ceiling find the least key pairs greater than or equal to key
+func (tree *splayTree) Ceiling(key string) (ceilingKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Lower find the greatest key pairs strictly less than key
+func (tree *splayTree) Lower(key string) (lowerKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.floor(key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Higher find the least key pairs strictly greater than key
+func (tree *splayTree) Higher(key string) (higherKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// the greatest node less than key, less than or equal to key when inclusive
+func (tree *splayTree) floor(key string, inclusive bool) *splayNode {
+	var candidate *splayNode
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp > 0 {
+			candidate = node
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// the least node greater than key, greater than or equal to key when inclusive
+func (tree *splayTree) ceiling(key string, inclusive bool) *splayNode {
+	var candidate *splayNode
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp < 0 {
+			candidate = node
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// Rank num of keys strictly less than key
+func (tree *splayTree) Rank(key string) int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	var rank, n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp > 0 {
+			rank += node.left.treeSize() + 1
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return rank
+}
+
+// Select find the i-th smallest key pairs, i start from 0
+func (tree *splayTree) Select(i int64) (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if i < 0 || i >= tree.root.treeSize() {
+		return
+	}
+
+	node := tree.root
+	for node != nil {
+		leftSize := node.left.treeSize()
+		if i < leftSize {
+			node = node.left
+		} else if i == leftSize {
+			return node.k, node.v, true
+		} else {
+			i = i - leftSize - 1
+			node = node.right
+		}
+	}
+
+	return
+}
+
+// Get splay the key to root, take the write lock
+func (tree *splayTree) Get(key string) (value interface{}, exist bool) {
+	if tree.readOnly {
+		tree.RLock()
+		defer tree.RUnlock()
+	} else {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	tree.stats.get(key)
+
+	return tree.get(key)
+}
+
+// get value of key without lock, caller should lock first
+func (tree *splayTree) get(key string) (value interface{}, exist bool) {
+	if node := tree.access(key); node != nil {
+		return node.v, true
+	}
+
+	return
+}
+
+// Contains splay the key to root, take the write lock
+func (tree *splayTree) Contains(key string) (exist bool) {
+	_, exist = tree.Get(key)
+	return
+}
+
+func (tree *splayTree) Len() int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.len
+}
+
+func (tree *splayTree) GetInt(key string) (value int, exist bool, err error) {
+	return getInt(tree.Get, key)
+}
+
+func (tree *splayTree) GetInt64(key string) (value int64, exist bool, err error) {
+	return getInt64(tree.Get, key)
+}
+
+func (tree *splayTree) GetString(key string) (value string, exist bool, err error) {
+	return getString(tree.Get, key)
+}
+
+func (tree *splayTree) GetFloat64(key string) (value float64, exist bool, err error) {
+	return getFloat64(tree.Get, key)
+}
+
+func (tree *splayTree) GetBytes(key string) (value []byte, exist bool, err error) {
+	return getBytes(tree.Get, key)
+}
+
+func (tree *splayTree) KeySortedList() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, nil, func(key string, value interface{}) bool {
+			keyList = append(keyList, key)
+			return true
+		})
+	}
+
+	return keyList
+}
+
+func (tree *splayTree) KeySortedListDesc() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	if tree.root != nil {
+		descend(tree.root, func(key string, value interface{}) bool {
+			keyList = append(keyList, key)
+			return true
+		})
+	}
+
+	return keyList
+}
+
+// Check binary search tree by key and size is right, splay tree has no balance rule
+func (tree *splayTree) Check() bool {
+	if tree == nil || tree.root == nil {
+		return true
+	}
+
+	if tree.root.size != tree.len {
+		fmt.Printf("root size %d != len %d\n", tree.root.size, tree.len)
+		return false
+	}
+
+	return tree.root.isBST(tree.c, nil, nil)
+}
+
+// check sub tree, all keys in (lo, hi), nil means no bound
+func (node *splayNode) isBST(compare comparator, lo, hi *string) bool {
+	if node == nil {
+		return true
+	}
+
+	if (lo != nil && compare(*lo, node.k) >= 0) || (hi != nil && compare(node.k, *hi) >= 0) {
+		fmt.Printf("key %s is not sorted\n", node.k)
+		return false
+	}
+
+	if node.size != node.left.treeSize()+node.right.treeSize()+1 {
+		fmt.Printf("size %d != %d+%d+1\n", node.size, node.left.treeSize(), node.right.treeSize())
+		return false
+	}
+
+	if node.height != nodeHeight(node.left.treeHeight(), node.right.treeHeight()) {
+		fmt.Printf("height %d, left %d, right %d\n", node.height, node.left.treeHeight(), node.right.treeHeight())
+		return false
+	}
+
+	return node.left.isBST(compare, lo, &node.k) && node.right.isBST(compare, &node.k, hi)
+}
+
+func (node *splayNode) leftOf() bsTreeNode {
+	if node.left == nil {
+		return nil
+	}
+
+	return node.left
+}
+
+func (node *splayNode) rightOf() bsTreeNode {
+	if node.right == nil {
+		return nil
+	}
+
+	return node.right
+}
+
+// not check node nil, may be panic, user should deal by oneself
+func (node *splayNode) values() (key string, value interface{}) {
+	return node.k, node.v
+}
+
+func (tree *splayTree) KeyList() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	iterator := tree.iterator()
+	for iterator.HasNext() {
+		k, _ := iterator.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList
+}
+
+// Iterator layer order of the shape when created, later splay not change it
+func (tree *splayTree) Iterator() MapIterator {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.share()
+	return tree.iterator()
+}
+
+// layer order iterator, without lock
+func (tree *splayTree) iterator() MapIterator {
+	q := new(linkQueue)
+	q.bind(&tree.modCount)
+	if tree.root != nil {
+		q.add(tree.root)
+	}
+	return q
+}
+
+// SafeIterator sorted iterator safe under concurrent write, snapshot mode is consistent, weak mode find next key every step
+func (tree *splayTree) SafeIterator(mode IteratorMode) MapIterator {
+	return newSafeIterator(tree, mode)
+}
+
+// AscendIterator iterator sorted by key, from min to max
+// nodes are shared when created, so Get or update value by other goroutines not break it
+func (tree *splayTree) AscendIterator() MapIterator {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.share()
+	s := new(linkStack)
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// DescendIterator iterator sorted by key, from max to min
+func (tree *splayTree) DescendIterator() MapIterator {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.share()
+	s := &linkStack{desc: true}
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// Range iterator key between from and to, sorted by key
+func (tree *splayTree) Range(from, to string, opt RangeOption) MapIterator {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.share()
+	return tree.rangeIterator(from, to, opt)
+}
+
+// range iterator without lock
+func (tree *splayTree) rangeIterator(from, to string, opt RangeOption) MapIterator {
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newRangeIterator(root, tree.c, from, to, opt)
+	it.bind(&tree.modCount)
+	return it
+}
+
+// Cursor bidirectional cursor, before min key at first
+func (tree *splayTree) Cursor() Cursor {
+	return newCursor(tree)
+}
+
+// PrefixIterator iterator key with prefix, sorted by key
+func (tree *splayTree) PrefixIterator(prefix string) (MapIterator, error) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.share()
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return it, nil
+}
+
+// KeysWithPrefix key with prefix out to list sorted
+func (tree *splayTree) KeysWithPrefix(prefix string) ([]string, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keyList := make([]string, 0)
+	for it.HasNext() {
+		k, _ := it.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList, nil
+}
+
+func (tree *splayTree) prefixIterator(prefix string) (*rangeIterator, error) {
+	if !isDefaultComparator(tree.c) {
+		return nil, ErrPrefixComparator
+	}
+
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newPrefixIterator(root, tree.c, prefix)
+	it.bind(&tree.modCount)
+	return it, nil
+}
+
+// DeleteRange delete keys which from <= key <= to, return num of deleted keys
+func (tree *splayTree) DeleteRange(from, to string) int64 {
+	return deleteRange(tree, from, to)
+}
+
+// PopMin find min key pairs and delete it
+func (tree *splayTree) PopMin() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	var buf [64]*splayNode
+	path := buf[:0]
+	for node := tree.root; node != nil; node = node.left {
+		path = append(path, node)
+	}
+
+	key = path[len(path)-1].k
+	return key, tree.deleteNode(path), true
+}
+
+// PopMax find max key pairs and delete it
+func (tree *splayTree) PopMax() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	var buf [64]*splayNode
+	path := buf[:0]
+	for node := tree.root; node != nil; node = node.right {
+		path = append(path, node)
+	}
+
+	key = path[len(path)-1].k
+	return key, tree.deleteNode(path), true
+}
+
+// Ascend walk all key pairs from min to max, stop when fn return false
+func (tree *splayTree) Ascend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, nil, fn)
+	}
+}
+
+// Descend walk all key pairs from max to min, stop when fn return false
+func (tree *splayTree) Descend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		descend(tree.root, fn)
+	}
+}
+
+// AscendGreaterOrEqual walk key pairs which pivot <= key, stop when fn return false
+func (tree *splayTree) AscendGreaterOrEqual(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &pivot, nil, fn)
+	}
+}
+
+// AscendLessThan walk key pairs which key < pivot, stop when fn return false
+func (tree *splayTree) AscendLessThan(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, &pivot, fn)
+	}
+}
+
+// AscendRange walk key pairs which greaterOrEqual <= key < lessThan, stop when fn return false
+func (tree *splayTree) AscendRange(greaterOrEqual, lessThan string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &greaterOrEqual, &lessThan, fn)
+	}
+}
+
+func (tree *splayTree) SetComparator(c comparator) Map {
+	tree.Lock()
+	defer tree.Unlock()
+	if tree.len == 0 {
+		tree.c = c
+	}
+
+	return tree
+}
+
+// Snapshot read only view of tree now, cost O(1), Get on snapshot not splay
+// all nodes now are shared with snapshot, later splay and write copy the path rather than change them
+func (tree *splayTree) Snapshot() ReadOnlyMap {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.share()
+	return readOnlyMap{&splayTree{
+		c:        tree.c,
+		root:     tree.root,
+		len:      tree.len,
+		readOnly: true,
+	}}
+}
+
+// Begin transaction, all writes apply on commit under one lock
+func (tree *splayTree) Begin() Txn {
+	return beginTxn(tree)
+}
+
+// Compute find key once, put value return by fn, delete key when fn return keep false
+func (tree *splayTree) Compute(key string, fn ComputeFunc) (value interface{}, exist bool) {
+	return tree.computeOp(key, fn.op())
+}
+
+// compute with lock, key unchanged by fn is still splayed like Get, but no event and no count
+func (tree *splayTree) computeOp(key string, fn computeOpFunc) (value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	var buf [64]*splayNode
+	node, cmp, path := tree.findPath(key, buf[:0])
+
+	// key not exist, the last node of path is the place to insert
+	if node == nil {
+		value, op := fn(nil, false)
+		if op != computePut {
+			tree.splayPath(path)
+			return nil, false
+		}
+
+		tree.insert(path, cmp, key, value)
+		return value, true
+	}
+
+	value, op := fn(node.v, true)
+	switch op {
+	case computePut:
+		tree.update(append(path, node), value)
+		return value, true
+	case computeDelete:
+		tree.deleteNode(append(path, node))
+		return nil, false
+	}
+
+	tree.splayPath(append(path, node))
+	return node.v, true
+}
+
+// PutIfAbsent put if key not exist, otherwise return the exist value
+func (tree *splayTree) PutIfAbsent(key string, value interface{}) (actual interface{}, loaded bool) {
+	return putIfAbsent(tree.computeOp, key, value)
+}
+
+// Replace put only if key exist, return the old value
+func (tree *splayTree) Replace(key string, value interface{}) (old interface{}, replaced bool) {
+	return replace(tree.computeOp, key, value)
+}
+
+// CompareAndSwap put new only if value of key == old
+func (tree *splayTree) CompareAndSwap(key string, old, new interface{}) (swapped bool) {
+	return compareAndSwap(tree.computeOp, key, old, new)
+}
+
+// CompareAndDelete delete only if value of key == old
+func (tree *splayTree) CompareAndDelete(key string, old interface{}) (deleted bool) {
+	return compareAndDelete(tree.computeOp, key, old)
+}
+
+// Watch watch put and delete of key, channel closed after ctx done
+func (tree *splayTree) Watch(ctx context.Context, key string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, key, false, opt)
+}
+
+// WatchPrefix watch put and delete of keys with prefix
+func (tree *splayTree) WatchPrefix(ctx context.Context, prefix string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, prefix, true, opt)
+}
@@ -0,0 +1,169 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import "strings"
+
+// use stack implement sorted iterator
+// only keep one path of tree, so memory is O(logN), every Next is O(1) amortized
+type linkStack struct {
+	root     *linkNode // 栈顶
+	size     int       // 栈的元素数量
+	desc     bool      // iterator from max to min
+	modGuard           // fail fast when map modified
+}
+
+// HasNext has next, stack size > 0
+func (stack *linkStack) HasNext() bool {
+	return stack.check() && stack.size > 0
+}
+
+func (stack *linkStack) Next() (key string, value interface{}) {
+	// map modified, node in stack may be dangling, Err will return ErrConcurrentModification
+	if !stack.check() {
+		return
+	}
+
+	// 出栈的节点就是当前最小（降序时最大）的节点
+	element := stack.pop()
+
+	// panic here
+	if element == nil {
+		panic("Next() empty")
+	}
+
+	// 比它大的下一批节点在右子树的左链上，降序时反过来
+	if stack.desc {
+		stack.pushPath(element.leftOf())
+	} else {
+		stack.pushPath(element.rightOf())
+	}
+
+	return element.values()
+}
+
+// 沿着左链一直入栈，降序时沿着右链
+func (stack *linkStack) pushPath(node bsTreeNode) {
+	for node != nil {
+		stack.push(node)
+		if stack.desc {
+			node = node.rightOf()
+		} else {
+			node = node.leftOf()
+		}
+	}
+}
+
+// 入栈
+func (stack *linkStack) push(v bsTreeNode) {
+	stack.root = &linkNode{
+		next:  stack.root,
+		value: v,
+	}
+
+	stack.size++
+}
+
+// 出栈
+func (stack *linkStack) pop() bsTreeNode {
+	if stack.size == 0 {
+		return nil
+	}
+
+	topNode := stack.root
+	stack.root = topNode.next
+	stack.size--
+
+	return topNode.value
+}
+
+// 找到第一个大于等于 key（exclusive 时大于 key）的节点，路径上比 key 大的节点都入栈
+// only support asc
+func (stack *linkStack) seek(node bsTreeNode, c comparator, key string, exclusive bool) {
+	for node != nil {
+		k, _ := node.values()
+		cmp := c(k, key)
+		if cmp > 0 || (cmp == 0 && !exclusive) {
+			// 该节点在范围内，入栈，继续往左找更小的
+			stack.push(node)
+			node = node.leftOf()
+		} else {
+			node = node.rightOf()
+		}
+	}
+}
+
+// use stack implement range iterator
+type rangeIterator struct {
+	linkStack
+	within func(key string) bool // key still not reach the upper bound, nil means no upper bound
+}
+
+// build a range iterator on tree, empty tree should pass nil rather than a nil node pointer
+func newRangeIterator(root bsTreeNode, c comparator, from, to string, opt RangeOption) *rangeIterator {
+	it := new(rangeIterator)
+	if !opt.ToUnbounded {
+		it.within = func(key string) bool {
+			cmp := c(key, to)
+			return cmp < 0 || (cmp == 0 && !opt.ToExclusive)
+		}
+	}
+
+	if root == nil {
+		return it
+	}
+
+	if opt.FromUnbounded {
+		it.pushPath(root)
+	} else {
+		it.seek(root, c, from, opt.FromExclusive)
+	}
+
+	return it
+}
+
+// build a prefix iterator on tree, keys with same prefix must be together, start from prefix, stop at the first key without prefix
+func newPrefixIterator(root bsTreeNode, c comparator, prefix string) *rangeIterator {
+	it := &rangeIterator{
+		within: func(key string) bool {
+			return strings.HasPrefix(key, prefix)
+		},
+	}
+
+	if root != nil {
+		it.seek(root, c, prefix, false)
+	}
+
+	return it
+}
+
+// HasNext stack top still less than upper bound
+func (it *rangeIterator) HasNext() bool {
+	if !it.check() || it.size == 0 {
+		return false
+	}
+
+	if it.within == nil {
+		return true
+	}
+
+	k, _ := it.root.value.values()
+	return it.within(k)
+}
+
+func (it *rangeIterator) Next() (key string, value interface{}) {
+	// map modified, Err will return ErrConcurrentModification
+	if !it.check() {
+		return
+	}
+
+	// panic here
+	if !it.HasNext() {
+		panic("Next() empty")
+	}
+
+	return it.linkStack.Next()
+}
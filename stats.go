@@ -0,0 +1,171 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import (
+	"encoding/json"
+	"math"
+	"sync/atomic"
+)
+
+// Stats live counters of map, counters only grow since map created
+type Stats struct {
+	Len            int64   // key pairs num
+	Height         int64   // height of tree, levels of skip list
+	HeightBound    float64 // theoretical max height of tree for Len, 0 when no hard bound such as skip list
+	Gets           int64   // num of point lookup, Get and Contains
+	Puts           int64   // num of key pairs add or update by any write
+	Deletes        int64   // num of key pairs delete by any write
+	Compares       int64   // num of comparator call when finding key, range bound check of iterators not count
+	LeftRotations  int64   // num of left rotation, b-tree borrow key pairs from right sibling
+	RightRotations int64   // num of right rotation, b-tree borrow key pairs from left sibling
+	Recolors       int64   // num of node color change, only rbt
+	Rebalances     int64   // num of node out of balance and fixed, avl and wbt by rotation, scapegoat by rebuilding sub tree
+}
+
+// num of stripes of read counters, power of 2
+const statStripes = 8
+
+// gets and compares of keys hashed to one stripe, padded to a cache line
+// so readers of different keys not fight for the same line
+type statStripe struct {
+	gets     int64
+	compares int64
+	_        [48]byte
+}
+
+// count gets and compares or not, only benchmark turn it off to measure the cost of counting on read path
+var countReads = true
+
+// counters of map, all change by atomic, so reader can count without write lock
+// gets and compares change on every lookup under read lock, so they split into stripes by key
+type mapStats struct {
+	reads          [statStripes]statStripe
+	puts           int64
+	deletes        int64
+	leftRotations  int64
+	rightRotations int64
+	recolors       int64
+	rebalances     int64
+}
+
+// load all counters to Stats
+func (s *mapStats) load() Stats {
+	var gets, compares int64
+	for i := range s.reads {
+		gets += atomic.LoadInt64(&s.reads[i].gets)
+		compares += atomic.LoadInt64(&s.reads[i].compares)
+	}
+
+	return Stats{
+		Gets:           gets,
+		Puts:           atomic.LoadInt64(&s.puts),
+		Deletes:        atomic.LoadInt64(&s.deletes),
+		Compares:       compares,
+		LeftRotations:  atomic.LoadInt64(&s.leftRotations),
+		RightRotations: atomic.LoadInt64(&s.rightRotations),
+		Recolors:       atomic.LoadInt64(&s.recolors),
+		Rebalances:     atomic.LoadInt64(&s.rebalances),
+	}
+}
+
+// stripe of key
+func (s *mapStats) stripe(key string) *statStripe {
+	return &s.reads[fnvHash(key)&(statStripes-1)]
+}
+
+// count one point lookup of key
+func (s *mapStats) get(key string) {
+	if !countReads {
+		return
+	}
+
+	atomic.AddInt64(&s.stripe(key).gets, 1)
+}
+
+// count compares of one search for key, add once so concurrent readers touch the counter less
+func (s *mapStats) compare(key string, n int64) {
+	if !countReads {
+		return
+	}
+
+	atomic.AddInt64(&s.stripe(key).compares, n)
+}
+
+// add counters of other, height use the max one, for map made of many trees
+func (s *Stats) add(other Stats) {
+	s.Len += other.Len
+	if other.Height > s.Height {
+		s.Height = other.Height
+	}
+	if other.HeightBound > s.HeightBound {
+		s.HeightBound = other.HeightBound
+	}
+	s.Gets += other.Gets
+	s.Puts += other.Puts
+	s.Deletes += other.Deletes
+	s.Compares += other.Compares
+	s.LeftRotations += other.LeftRotations
+	s.RightRotations += other.RightRotations
+	s.Recolors += other.Recolors
+	s.Rebalances += other.Rebalances
+}
+
+// height of node which children height are lh and rh
+func nodeHeight(lh, rh int64) int32 {
+	if lh > rh {
+		return int32(lh + 1)
+	}
+
+	return int32(rh + 1)
+}
+
+// max height of rbt with n key pairs: 2log(n+1)
+func rbHeightBound(n int64) float64 {
+	return 2 * math.Log2(float64(n+1))
+}
+
+// max height of avl with n key pairs: 1.44log(n+2)-0.328
+func avlHeightBound(n int64) float64 {
+	return 1.4405*math.Log2(float64(n+2)) - 0.3277
+}
+
+// max height of b-tree of min degree t with n key pairs: log_t((n+1)/2)+1
+func bTreeHeightBound(n int64, t int) float64 {
+	if n == 0 {
+		return 0
+	}
+
+	return math.Log(float64(n+1)/2)/math.Log(float64(t)) + 1
+}
+
+// max height of scapegoat tree which max len since last full rebuild is maxSize: log_{1/alpha}(maxSize)+1
+func scapegoatHeightBound(maxSize int64, alpha float64) float64 {
+	if maxSize == 0 {
+		return 0
+	}
+
+	return math.Log(float64(maxSize))/math.Log(1/alpha) + 1
+}
+
+// max height of weight balanced tree with n key pairs: log_{1/(1-alpha)}((n+1)/2)+1
+func wbtHeightBound(n int64, alpha float64) float64 {
+	if n == 0 {
+		return 0
+	}
+
+	return math.Log(float64(n+1)/2)/math.Log(1/(1-alpha)) + 1
+}
+
+// StatsVar expvar.Var of map, publish it by expvar.Publish(name, gomap.StatsVar(m.Stats))
+// stats read every time /debug/vars is visited
+type StatsVar func() Stats
+
+// String stats in json
+func (v StatsVar) String() string {
+	b, _ := json.Marshal(v())
+	return string(b)
+}
@@ -0,0 +1,1148 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrJoinOrder keys of map are not all less than keys of other when join
+var ErrJoinOrder = errors.New("keys of map must be less than keys of other")
+
+// TreapMap treap map can split and join in O(logN), maps split or joined share nodes and copy on write
+type TreapMap interface {
+	Map
+	Split(key string) (less, greaterOrEqual TreapMap) // split keys < key and keys >= key to two new maps, map not change
+	Join(other TreapMap) (TreapMap, error)            // new map of keys of map and other, keys of map must less than keys of other, both not change
+
+	treapRoot() (*treapNode, comparator) // root of a point in time view, make sure other of Join is a treap
+}
+
+// gen of all treaps, every treap has a unique gen, so split and joined treaps never change the shared nodes
+var treapGen uint64
+
+func nextTreapGen() uint64 {
+	return atomic.AddUint64(&treapGen, 1)
+}
+
+// Treap, binary search tree by key and max heap by random priority, so the shape is same as insert keys by priority order
+// expected height is O(logN), no hard bound
+type treap struct {
+	modCount     int64      // num of add or delete key, iterator use it to fail fast
+	c            comparator // tree key compare
+	root         *treapNode // tree root
+	len          int64      // tree key pairs num
+	seed         uint64     // state of random priority, same seed and same writes get the same shape
+	gen          uint64     // node gen not equal to it is shared, copy before change
+	watch        *watchHub  // subscribers of change, nil until first watch
+	stats        mapStats   // live counters
+	sync.RWMutex            // lock for concurrent safe, read lock for lookup
+}
+
+type treapNode struct {
+	k        string      // key
+	v        interface{} // value
+	left     *treapNode
+	right    *treapNode
+	priority uint64 // parent priority is greater than or equal to children
+	height   int32  // height of the sub tree, only for Height and Stats
+	size     int64  // key pairs num of the sub tree
+	gen      uint64 // gen of tree when node created or copied
+}
+
+// NewTreapMap new a treap map, priority of key is random by seed, same seed same shape
+func NewTreapMap(seed int64) TreapMap {
+	t := new(treap)
+	t.c = comparatorDefault
+	t.seed = uint64(seed)
+	t.gen = nextTreapGen()
+	return t
+}
+
+// random priority of new node, splitmix64, caller should hold write lock
+func (tree *treap) random() uint64 {
+	tree.seed += 0x9E3779B97F4A7C15
+	return mix64(tree.seed)
+}
+
+// output function of splitmix64, mix bits of x
+func mix64(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	return x ^ (x >> 31)
+}
+
+// height of sub tree, nil is 0
+func (node *treapNode) treeHeight() int64 {
+	if node == nil {
+		return 0
+	}
+
+	return int64(node.height)
+}
+
+// cal sub tree size
+func (node *treapNode) treeSize() int64 {
+	if node == nil {
+		return 0
+	}
+
+	return node.size
+}
+
+// Height root keep height of the whole tree, O(1)
+func (tree *treap) Height() int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.root.treeHeight()
+}
+
+// Stats live counters, treap has no hard height bound, expected height is about 3log(Len)
+func (tree *treap) Stats() Stats {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := tree.stats.load()
+	s.Len = tree.len
+	s.Height = tree.root.treeHeight()
+	return s
+}
+
+// node shared, copy it for tree to change, caller should link the copy to parent
+func (tree *treap) own(node *treapNode) *treapNode {
+	if node == nil || node.gen == tree.gen {
+		return node
+	}
+
+	n := new(treapNode)
+	*n = *node
+	n.gen = tree.gen
+	return n
+}
+
+// copy shared nodes of path from root, and link them again, so it is path copying
+func (tree *treap) ownPath(path []*treapNode) {
+	for i, node := range path {
+		n := tree.own(node)
+		if n == node {
+			continue
+		}
+
+		path[i] = n
+		if i == 0 {
+			tree.root = n
+		} else if path[i-1].left == node {
+			path[i-1].left = n
+		} else {
+			path[i-1].right = n
+		}
+	}
+}
+
+// find key in tree, without lock
+func (tree *treap) find(key string) *treapNode {
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 {
+			break
+		} else if cmp < 0 {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return node
+}
+
+// find key, return the node and ancestors of it from root, nil node when not found
+// cmp is compare of key and the last ancestor, key should insert to left of it when cmp less than 0
+func (tree *treap) findPath(key string, path []*treapNode) (node *treapNode, cmp int64, _ []*treapNode) {
+	var n int64
+	node = tree.root
+	for node != nil {
+		n++
+		cmp = tree.c(key, node.k)
+		if cmp == 0 {
+			break
+		}
+
+		path = append(path, node)
+		if cmp < 0 {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return node, cmp, path
+}
+
+// height of nodes in path from bottom to top, each node is parent of the next one, nodes should be owned
+func (tree *treap) fixHeight(path []*treapNode) {
+	for i := len(path) - 1; i >= 0; i-- {
+		path[i].height = nodeHeight(path[i].left.treeHeight(), path[i].right.treeHeight())
+	}
+}
+
+// h.left rise up, h should be owned, return the new top
+func (tree *treap) rotateRight(h *treapNode) *treapNode {
+	atomic.AddInt64(&tree.stats.rightRotations, 1)
+	x := tree.own(h.left)
+	h.left = x.right
+	x.right = h
+
+	x.size = h.size
+	h.size = h.left.treeSize() + h.right.treeSize() + 1
+	h.height = nodeHeight(h.left.treeHeight(), h.right.treeHeight())
+	x.height = nodeHeight(x.left.treeHeight(), x.right.treeHeight())
+	return x
+}
+
+// h.right rise up, h should be owned, return the new top
+func (tree *treap) rotateLeft(h *treapNode) *treapNode {
+	atomic.AddInt64(&tree.stats.leftRotations, 1)
+	x := tree.own(h.right)
+	h.right = x.left
+	x.left = h
+
+	x.size = h.size
+	h.size = h.left.treeSize() + h.right.treeSize() + 1
+	h.height = nodeHeight(h.left.treeHeight(), h.right.treeHeight())
+	x.height = nodeHeight(x.left.treeHeight(), x.right.treeHeight())
+	return x
+}
+
+func (tree *treap) Put(key string, value interface{}) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.put(key, value)
+}
+
+// put key pairs without lock, caller should lock first
+func (tree *treap) put(key string, value interface{}) {
+	var buf [64]*treapNode
+	node, cmp, path := tree.findPath(key, buf[:0])
+	if node != nil {
+		tree.update(append(path, node), value)
+		return
+	}
+
+	tree.insert(path, cmp, key, value)
+}
+
+// insert new node under the last node of path, then rotate it up until priority of parent is greater, without lock
+func (tree *treap) insert(path []*treapNode, cmp int64, key string, value interface{}) {
+	tree.ownPath(path)
+	node := &treapNode{
+		k:        key,
+		v:        value,
+		priority: tree.random(),
+		height:   1,
+		size:     1,
+		gen:      tree.gen,
+	}
+
+	if len(path) == 0 {
+		tree.root = node
+	} else if cmp < 0 {
+		path[len(path)-1].left = node
+	} else {
+		path[len(path)-1].right = node
+	}
+
+	// all ancestors size add 1
+	for _, p := range path {
+		p.size++
+	}
+
+	i := len(path) - 1
+	for ; i >= 0 && path[i].priority < node.priority; i-- {
+		p := path[i]
+		if p.left == node {
+			tree.rotateRight(p)
+		} else {
+			tree.rotateLeft(p)
+		}
+
+		// node take place of p
+		if i == 0 {
+			tree.root = node
+		} else if path[i-1].left == p {
+			path[i-1].left = node
+		} else {
+			path[i-1].right = node
+		}
+	}
+
+	// rotated nodes are fixed by rotation, ancestors above node now need height fixed
+	tree.fixHeight(path[:i+1])
+
+	tree.len++
+	atomic.AddInt64(&tree.modCount, 1)
+	atomic.AddInt64(&tree.stats.puts, 1)
+	tree.watch.put(key, value, nil, false)
+}
+
+// update value of the last node of path without lock
+func (tree *treap) update(path []*treapNode, value interface{}) {
+	tree.ownPath(path)
+	node := path[len(path)-1]
+	old := node.v
+	node.v = value
+	atomic.AddInt64(&tree.stats.puts, 1)
+	tree.watch.put(node.k, value, old, true)
+}
+
+func (tree *treap) Delete(key string) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.deleteKey(key)
+}
+
+// delete key without lock, caller should lock first
+func (tree *treap) deleteKey(key string) (value interface{}, exist bool) {
+	var buf [64]*treapNode
+	node, _, path := tree.findPath(key, buf[:0])
+	if node == nil {
+		return
+	}
+
+	return tree.deleteNode(append(path, node)), true
+}
+
+// delete the last node of path, rotate it down by the child of greater priority until it has one child, without lock
+func (tree *treap) deleteNode(path []*treapNode) (value interface{}) {
+	tree.ownPath(path)
+	node := path[len(path)-1]
+	key, value := node.k, node.v
+
+	// all ancestors size sub 1
+	for _, p := range path[:len(path)-1] {
+		p.size--
+	}
+
+	// where node link to
+	link := &tree.root
+	if len(path) > 1 {
+		if parent := path[len(path)-2]; parent.left == node {
+			link = &parent.left
+		} else {
+			link = &parent.right
+		}
+	}
+
+	// children rotated up, heights of them and ancestors are fixed after node removed
+	var buf [64]*treapNode
+	tops := buf[:0]
+	for node.left != nil && node.right != nil {
+		var top *treapNode
+		if node.left.priority > node.right.priority {
+			top = tree.rotateRight(node)
+			*link = top
+			link = &top.right
+		} else {
+			top = tree.rotateLeft(node)
+			*link = top
+			link = &top.left
+		}
+
+		// node will be deleted from sub tree of top
+		top.size--
+		tops = append(tops, top)
+	}
+
+	if node.left != nil {
+		*link = node.left
+	} else {
+		*link = node.right
+	}
+
+	tree.fixHeight(tops)
+	tree.fixHeight(path[:len(path)-1])
+
+	tree.len--
+	atomic.AddInt64(&tree.modCount, 1)
+	atomic.AddInt64(&tree.stats.deletes, 1)
+	tree.watch.delete(key, value)
+	return value
+}
+
+// MinKey find min key pairs
+func (tree *treap) MinKey() (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	node := tree.root.minNode()
+	return node.k, node.v, true
+}
+
+func (node *treapNode) minNode() *treapNode {
+	for node.left != nil {
+		node = node.left
+	}
+
+	return node
+}
+
+// MaxKey find max key pairs
+func (tree *treap) MaxKey() (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	node := tree.root.maxNode()
+	return node.k, node.v, true
+}
+
+func (node *treapNode) maxNode() *treapNode {
+	for node.right != nil {
+		node = node.right
+	}
+
+	return node
+}
+
+// Floor find the greatest key pairs less than or equal to key
+func (tree *treap) Floor(key string) (floorKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.floor(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Ceiling find the least key pairs greater than or equal to key
+func (tree *treap) Ceiling(key string) (ceilingKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Lower find the greatest key pairs strictly less than key
+func (tree *treap) Lower(key string) (lowerKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.floor(key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Higher find the least key pairs strictly greater than key
+func (tree *treap) Higher(key string) (higherKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// the greatest node less than key, less than or equal to key when inclusive
+func (tree *treap) floor(key string, inclusive bool) *treapNode {
+	var candidate *treapNode
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp > 0 {
+			candidate = node
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// the least node greater than key, greater than or equal to key when inclusive
+func (tree *treap) ceiling(key string, inclusive bool) *treapNode {
+	var candidate *treapNode
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp < 0 {
+			candidate = node
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// Rank num of keys strictly less than key
+func (tree *treap) Rank(key string) int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	var rank, n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp > 0 {
+			rank += node.left.treeSize() + 1
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return rank
+}
+
+// Select find the i-th smallest key pairs, i start from 0
+func (tree *treap) Select(i int64) (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if i < 0 || i >= tree.root.treeSize() {
+		return
+	}
+
+	node := tree.root
+	for node != nil {
+		leftSize := node.left.treeSize()
+		if i < leftSize {
+			node = node.left
+		} else if i == leftSize {
+			return node.k, node.v, true
+		} else {
+			i = i - leftSize - 1
+			node = node.right
+		}
+	}
+
+	return
+}
+
+func (tree *treap) Get(key string) (value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
+
+	return tree.get(key)
+}
+
+// get value of key without lock, caller should lock first
+func (tree *treap) get(key string) (value interface{}, exist bool) {
+	if node := tree.find(key); node != nil {
+		return node.v, true
+	}
+
+	return
+}
+
+func (tree *treap) Contains(key string) (exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
+
+	return tree.find(key) != nil
+}
+
+func (tree *treap) Len() int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.len
+}
+
+func (tree *treap) GetInt(key string) (value int, exist bool, err error) {
+	return getInt(tree.Get, key)
+}
+
+func (tree *treap) GetInt64(key string) (value int64, exist bool, err error) {
+	return getInt64(tree.Get, key)
+}
+
+func (tree *treap) GetString(key string) (value string, exist bool, err error) {
+	return getString(tree.Get, key)
+}
+
+func (tree *treap) GetFloat64(key string) (value float64, exist bool, err error) {
+	return getFloat64(tree.Get, key)
+}
+
+func (tree *treap) GetBytes(key string) (value []byte, exist bool, err error) {
+	return getBytes(tree.Get, key)
+}
+
+func (tree *treap) KeySortedList() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, nil, func(key string, value interface{}) bool {
+			keyList = append(keyList, key)
+			return true
+		})
+	}
+
+	return keyList
+}
+
+func (tree *treap) KeySortedListDesc() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	if tree.root != nil {
+		descend(tree.root, func(key string, value interface{}) bool {
+			keyList = append(keyList, key)
+			return true
+		})
+	}
+
+	return keyList
+}
+
+// Check binary search tree by key, max heap by priority, size is right
+func (tree *treap) Check() bool {
+	if tree == nil || tree.root == nil {
+		return true
+	}
+
+	if tree.root.size != tree.len {
+		fmt.Printf("root size %d != len %d\n", tree.root.size, tree.len)
+		return false
+	}
+
+	return tree.root.isTreap(tree.c, nil, nil)
+}
+
+// check sub tree, all keys in (lo, hi), nil means no bound
+func (node *treapNode) isTreap(compare comparator, lo, hi *string) bool {
+	if node == nil {
+		return true
+	}
+
+	if (lo != nil && compare(*lo, node.k) >= 0) || (hi != nil && compare(node.k, *hi) >= 0) {
+		fmt.Printf("key %s is not sorted\n", node.k)
+		return false
+	}
+
+	if node.size != node.left.treeSize()+node.right.treeSize()+1 {
+		fmt.Printf("size %d != %d+%d+1\n", node.size, node.left.treeSize(), node.right.treeSize())
+		return false
+	}
+
+	if node.height != nodeHeight(node.left.treeHeight(), node.right.treeHeight()) {
+		fmt.Printf("height %d, left %d, right %d\n", node.height, node.left.treeHeight(), node.right.treeHeight())
+		return false
+	}
+
+	if (node.left != nil && node.left.priority > node.priority) || (node.right != nil && node.right.priority > node.priority) {
+		fmt.Printf("child priority greater than %s\n", node.k)
+		return false
+	}
+
+	return node.left.isTreap(compare, lo, &node.k) && node.right.isTreap(compare, &node.k, hi)
+}
+
+func (node *treapNode) leftOf() bsTreeNode {
+	if node.left == nil {
+		return nil
+	}
+
+	return node.left
+}
+
+func (node *treapNode) rightOf() bsTreeNode {
+	if node.right == nil {
+		return nil
+	}
+
+	return node.right
+}
+
+// not check node nil, may be panic, user should deal by oneself
+func (node *treapNode) values() (key string, value interface{}) {
+	return node.k, node.v
+}
+
+func (tree *treap) KeyList() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	iterator := tree.iterator()
+	for iterator.HasNext() {
+		k, _ := iterator.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList
+}
+
+func (tree *treap) Iterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.iterator()
+}
+
+// layer order iterator, without lock
+func (tree *treap) iterator() MapIterator {
+	q := new(linkQueue)
+	q.bind(&tree.modCount)
+	if tree.root != nil {
+		q.add(tree.root)
+	}
+	return q
+}
+
+// SafeIterator sorted iterator safe under concurrent write, snapshot mode is consistent, weak mode find next key every step
+func (tree *treap) SafeIterator(mode IteratorMode) MapIterator {
+	return newSafeIterator(tree, mode)
+}
+
+// AscendIterator iterator sorted by key, from min to max
+func (tree *treap) AscendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := new(linkStack)
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// DescendIterator iterator sorted by key, from max to min
+func (tree *treap) DescendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := &linkStack{desc: true}
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// Range iterator key between from and to, sorted by key
+func (tree *treap) Range(from, to string, opt RangeOption) MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.rangeIterator(from, to, opt)
+}
+
+// range iterator without lock
+func (tree *treap) rangeIterator(from, to string, opt RangeOption) MapIterator {
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newRangeIterator(root, tree.c, from, to, opt)
+	it.bind(&tree.modCount)
+	return it
+}
+
+// Cursor bidirectional cursor, before min key at first
+func (tree *treap) Cursor() Cursor {
+	return newCursor(tree)
+}
+
+// PrefixIterator iterator key with prefix, sorted by key
+func (tree *treap) PrefixIterator(prefix string) (MapIterator, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return it, nil
+}
+
+// KeysWithPrefix key with prefix out to list sorted
+func (tree *treap) KeysWithPrefix(prefix string) ([]string, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keyList := make([]string, 0)
+	for it.HasNext() {
+		k, _ := it.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList, nil
+}
+
+func (tree *treap) prefixIterator(prefix string) (*rangeIterator, error) {
+	if !isDefaultComparator(tree.c) {
+		return nil, ErrPrefixComparator
+	}
+
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newPrefixIterator(root, tree.c, prefix)
+	it.bind(&tree.modCount)
+	return it, nil
+}
+
+// DeleteRange delete keys which from <= key <= to, return num of deleted keys
+func (tree *treap) DeleteRange(from, to string) int64 {
+	return deleteRange(tree, from, to)
+}
+
+// PopMin find min key pairs and delete it
+func (tree *treap) PopMin() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	var buf [64]*treapNode
+	path := buf[:0]
+	for node := tree.root; node != nil; node = node.left {
+		path = append(path, node)
+	}
+
+	key = path[len(path)-1].k
+	return key, tree.deleteNode(path), true
+}
+
+// PopMax find max key pairs and delete it
+func (tree *treap) PopMax() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	var buf [64]*treapNode
+	path := buf[:0]
+	for node := tree.root; node != nil; node = node.right {
+		path = append(path, node)
+	}
+
+	key = path[len(path)-1].k
+	return key, tree.deleteNode(path), true
+}
+
+// Ascend walk all key pairs from min to max, stop when fn return false
+func (tree *treap) Ascend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, nil, fn)
+	}
+}
+
+// Descend walk all key pairs from max to min, stop when fn return false
+func (tree *treap) Descend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		descend(tree.root, fn)
+	}
+}
+
+// AscendGreaterOrEqual walk key pairs which pivot <= key, stop when fn return false
+func (tree *treap) AscendGreaterOrEqual(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &pivot, nil, fn)
+	}
+}
+
+// AscendLessThan walk key pairs which key < pivot, stop when fn return false
+func (tree *treap) AscendLessThan(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, &pivot, fn)
+	}
+}
+
+// AscendRange walk key pairs which greaterOrEqual <= key < lessThan, stop when fn return false
+func (tree *treap) AscendRange(greaterOrEqual, lessThan string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &greaterOrEqual, &lessThan, fn)
+	}
+}
+
+func (tree *treap) SetComparator(c comparator) Map {
+	tree.Lock()
+	defer tree.Unlock()
+	if tree.len == 0 {
+		tree.c = c
+	}
+
+	return tree
+}
+
+// Snapshot read only view of tree now, cost O(1)
+// all nodes now are shared with snapshot, later write copy the path rather than change them
+func (tree *treap) Snapshot() ReadOnlyMap {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.gen = nextTreapGen()
+	return readOnlyMap{&treap{
+		c:    tree.c,
+		root: tree.root,
+		len:  tree.len,
+	}}
+}
+
+// root of point in time view, nodes now are shared, tree copy them before change
+func (tree *treap) treapRoot() (*treapNode, comparator) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.gen = nextTreapGen()
+	return tree.root, tree.c
+}
+
+// new empty treap for split or join, seed mixed from seed of tree and salt, seed of tree not change
+func (tree *treap) newTreap(salt uint64) *treap {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return &treap{
+		c:    tree.c,
+		seed: mix64(tree.seed ^ mix64(salt)),
+		gen:  nextTreapGen(),
+	}
+}
+
+// Split keys < key and keys >= key to two new maps, cost O(logN)
+// only nodes on the path of key are copied, others are shared, key pairs and seed of map not change
+// nodes of map now become shared, so later write on map copy the touched path
+func (tree *treap) Split(key string) (less, greaterOrEqual TreapMap) {
+	root, c := tree.treapRoot()
+	l, r := tree.newTreap(1), tree.newTreap(2)
+
+	// copied nodes, sizes and heights of them are fixed from bottom to top at last
+	copied := make([]*treapNode, 0, 64)
+	lLink, rLink := &l.root, &r.root
+	for node := root; node != nil; {
+		if c(node.k, key) < 0 {
+			// node and left sub tree go to l, right sub tree is split again
+			n := l.own(node)
+			*lLink = n
+			lLink = &n.right
+			copied = append(copied, n)
+			node = node.right
+		} else {
+			n := r.own(node)
+			*rLink = n
+			rLink = &n.left
+			copied = append(copied, n)
+			node = node.left
+		}
+	}
+	*lLink, *rLink = nil, nil
+
+	for i := len(copied) - 1; i >= 0; i-- {
+		n := copied[i]
+		n.size = n.left.treeSize() + n.right.treeSize() + 1
+		n.height = nodeHeight(n.left.treeHeight(), n.right.treeHeight())
+	}
+
+	l.len, r.len = l.root.treeSize(), r.root.treeSize()
+	return l, r
+}
+
+// Join new map of keys of map and other, keys of map must be less than keys of other, otherwise return ErrJoinOrder
+// cost O(logN), only nodes on the right path of map and left path of other are copied, key pairs and seeds of maps not change
+func (tree *treap) Join(other TreapMap) (TreapMap, error) {
+	a, c := tree.treapRoot()
+	b, _ := other.treapRoot()
+	if a != nil && b != nil && c(a.maxNode().k, b.minNode().k) >= 0 {
+		return nil, ErrJoinOrder
+	}
+
+	t := tree.newTreap(3)
+	copied := make([]*treapNode, 0, 64)
+	link := &t.root
+	for a != nil && b != nil {
+		// the greater priority is the top
+		if a.priority > b.priority {
+			n := t.own(a)
+			*link = n
+			link = &n.right
+			copied = append(copied, n)
+			a = a.right
+		} else {
+			n := t.own(b)
+			*link = n
+			link = &n.left
+			copied = append(copied, n)
+			b = b.left
+		}
+	}
+
+	if a != nil {
+		*link = a
+	} else {
+		*link = b
+	}
+
+	for i := len(copied) - 1; i >= 0; i-- {
+		n := copied[i]
+		n.size = n.left.treeSize() + n.right.treeSize() + 1
+		n.height = nodeHeight(n.left.treeHeight(), n.right.treeHeight())
+	}
+
+	t.len = t.root.treeSize()
+	return t, nil
+}
+
+// Begin transaction, all writes apply on commit under one lock
+func (tree *treap) Begin() Txn {
+	return beginTxn(tree)
+}
+
+// Compute find key once, put value return by fn, delete key when fn return keep false
+func (tree *treap) Compute(key string, fn ComputeFunc) (value interface{}, exist bool) {
+	return tree.computeOp(key, fn.op())
+}
+
+// compute with lock, nothing write when fn leave the key unchanged
+func (tree *treap) computeOp(key string, fn computeOpFunc) (value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	var buf [64]*treapNode
+	node, cmp, path := tree.findPath(key, buf[:0])
+
+	// key not exist, the last node of path is the place to insert
+	if node == nil {
+		value, op := fn(nil, false)
+		if op != computePut {
+			return nil, false
+		}
+
+		tree.insert(path, cmp, key, value)
+		return value, true
+	}
+
+	value, op := fn(node.v, true)
+	switch op {
+	case computePut:
+		tree.update(append(path, node), value)
+		return value, true
+	case computeDelete:
+		tree.deleteNode(append(path, node))
+		return nil, false
+	}
+
+	return node.v, true
+}
+
+// PutIfAbsent put if key not exist, otherwise return the exist value
+func (tree *treap) PutIfAbsent(key string, value interface{}) (actual interface{}, loaded bool) {
+	return putIfAbsent(tree.computeOp, key, value)
+}
+
+// Replace put only if key exist, return the old value
+func (tree *treap) Replace(key string, value interface{}) (old interface{}, replaced bool) {
+	return replace(tree.computeOp, key, value)
+}
+
+// CompareAndSwap put new only if value of key == old
+func (tree *treap) CompareAndSwap(key string, old, new interface{}) (swapped bool) {
+	return compareAndSwap(tree.computeOp, key, old, new)
+}
+
+// CompareAndDelete delete only if value of key == old
+func (tree *treap) CompareAndDelete(key string, old interface{}) (deleted bool) {
+	return compareAndDelete(tree.computeOp, key, old)
+}
+
+// Watch watch put and delete of key, channel closed after ctx done
+func (tree *treap) Watch(ctx context.Context, key string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, key, false, opt)
+}
+
+// WatchPrefix watch put and delete of keys with prefix
+func (tree *treap) WatchPrefix(ctx context.Context, prefix string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, prefix, true, opt)
+}
@@ -0,0 +1,153 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ErrTxnDone commit a transaction which is already commit or rollback
+var ErrTxnDone = errors.New("transaction already commit or rollback")
+
+// ErrTxnConflict commit a transaction which read key changed by other writer before commit, nothing is written
+var ErrTxnConflict = errors.New("transaction conflict, key read by it changed before commit")
+
+// Txn transaction of map, writes only keep in txn until commit
+// reads see writes of txn first, other keys read from map directly and remember the value
+// commit check under the lock that every key read still has the value seen, otherwise ErrTxnConflict
+// txn is not concurrent safe, use it in one goroutine
+type Txn interface {
+	Get(key string) (value interface{}, exist bool) // get value from key, see writes of txn, read the same key again see the same value
+	Put(key string, value interface{})              // put key pairs in txn
+	Delete(key string)                              // delete a key in txn
+	Commit() error                                  // apply all writes to map under one lock, ErrTxnConflict when key read changed, start a new txn to retry
+	Rollback()                                      // drop all writes
+}
+
+// one write of txn
+type txnWrite struct {
+	value   interface{} // value of put
+	deleted bool        // write is delete
+}
+
+// value of key seen by txn when read from map
+type txnRead struct {
+	value interface{}
+	exist bool
+}
+
+// apply writes of txn to map under one lock, return ErrTxnConflict and write nothing when reads changed
+type txnCommit func(reads map[string]txnRead, writes map[string]txnWrite) error
+
+// txn keep the first read and the last write of every key
+type mapTxn struct {
+	m      Map                 // map of txn
+	reads  map[string]txnRead  // first read of every key from map
+	writes map[string]txnWrite // last write of every key
+	commit txnCommit           // apply writes to map under one lock
+	done   bool                // already commit or rollback
+}
+
+func newTxn(m Map, commit txnCommit) *mapTxn {
+	return &mapTxn{
+		m:      m,
+		reads:  make(map[string]txnRead),
+		writes: make(map[string]txnWrite),
+		commit: commit,
+	}
+}
+
+func (txn *mapTxn) Get(key string) (value interface{}, exist bool) {
+	if w, ok := txn.writes[key]; ok {
+		if w.deleted {
+			return nil, false
+		}
+
+		return w.value, true
+	}
+
+	if r, ok := txn.reads[key]; ok {
+		return r.value, r.exist
+	}
+
+	value, exist = txn.m.Get(key)
+	if !txn.done {
+		txn.reads[key] = txnRead{value: value, exist: exist}
+	}
+
+	return
+}
+
+// Put do nothing after commit or rollback
+func (txn *mapTxn) Put(key string, value interface{}) {
+	if txn.done {
+		return
+	}
+
+	txn.writes[key] = txnWrite{value: value}
+}
+
+// Delete do nothing after commit or rollback
+func (txn *mapTxn) Delete(key string) {
+	if txn.done {
+		return
+	}
+
+	txn.writes[key] = txnWrite{deleted: true}
+}
+
+// Commit ErrTxnDone if already commit or rollback, txn is done even if ErrTxnConflict
+func (txn *mapTxn) Commit() error {
+	if txn.done {
+		return ErrTxnDone
+	}
+
+	err := txn.commit(txn.reads, txn.writes)
+	txn.Rollback()
+	return err
+}
+
+func (txn *mapTxn) Rollback() {
+	txn.done = true
+	txn.reads = nil
+	txn.writes = nil
+}
+
+// check every key read by txn still has the value seen, get should not lock as caller hold the lock
+func checkReads(get getMethod, reads map[string]txnRead) error {
+	for key, r := range reads {
+		value, exist := get(key)
+		if exist != r.exist || (exist && !sameValue(value, r.value)) {
+			return ErrTxnConflict
+		}
+	}
+
+	return nil
+}
+
+// a and b are the same value, value can not compare such as slice and map is the same only if point to the same data
+func sameValue(a, b interface{}) bool {
+	ta := reflect.TypeOf(a)
+	if ta != reflect.TypeOf(b) {
+		return false
+	}
+
+	if ta == nil || ta.Comparable() {
+		return a == b
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch ta.Kind() {
+	case reflect.Slice:
+		return va.Pointer() == vb.Pointer() && va.Len() == vb.Len()
+	case reflect.Map, reflect.Func:
+		return va.Pointer() == vb.Pointer()
+	}
+
+	// struct or array hold slice or map, can not tell, so treat as changed
+	return false
+}
@@ -0,0 +1,264 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrVersionCompacted history of version is dropped by Compact
+var ErrVersionCompacted = errors.New("version is compacted")
+
+// ErrVersionNotExist version is negative or greater than the newest version
+var ErrVersionNotExist = errors.New("version not exist")
+
+// VersionedMap map keep history of key pairs, can read at a past version
+// every write make a new version, version of empty map is 0
+type VersionedMap interface {
+	Put(key string, value interface{}) (version int64)                          // put key pairs, return the new version
+	Delete(key string) (version int64)                                          // delete a key, return the new version
+	Get(key string) (value interface{}, exist bool)                             // get value from key at newest version
+	GetAt(key string, version int64) (value interface{}, exist bool, err error) // get value from key at version
+	IteratorAt(version int64) (MapIterator, error)                              // iterator key pairs at version, sorted, not hold lock
+	Compact(beforeVersion int64)                                                // drop history only for reading before version
+	Version() int64                                                             // newest version
+	Len() int64                                                                 // key pairs num at newest version
+}
+
+// one history of key
+type versionedValue struct {
+	version int64       // version of write
+	value   interface{} // value of put
+	deleted bool        // write is delete
+}
+
+// versioned map, keep history of every key in rbt, history sorted by version
+// history of a key only append or replace by a new one, never change in place, so snapshot of rbt can read it without lock
+type versionedMap struct {
+	m            Map   // key -> []versionedValue
+	version      int64 // newest version
+	compacted    int64 // read before it return ErrVersionCompacted
+	len          int64 // key pairs num at newest version
+	sync.RWMutex       // lock for concurrent safe, read lock for lookup
+}
+
+// NewVersionedMap new a versioned map on rbt
+func NewVersionedMap() VersionedMap {
+	return &versionedMap{
+		m: NewRBMap(),
+	}
+}
+
+// history of key, nil if not exist
+func (vm *versionedMap) history(key string) []versionedValue {
+	h, exist := vm.m.Get(key)
+	if !exist {
+		return nil
+	}
+
+	return h.([]versionedValue)
+}
+
+// last history write at or before version
+func lookupVersion(h []versionedValue, version int64) (value interface{}, exist bool) {
+	i := sort.Search(len(h), func(i int) bool {
+		return h[i].version > version
+	}) - 1
+
+	if i < 0 || h[i].deleted {
+		return nil, false
+	}
+
+	return h[i].value, true
+}
+
+func (vm *versionedMap) Put(key string, value interface{}) (version int64) {
+	vm.Lock()
+	defer vm.Unlock()
+
+	h := vm.history(key)
+	if len(h) == 0 || h[len(h)-1].deleted {
+		vm.len++
+	}
+
+	vm.version++
+	vm.m.Put(key, append(h, versionedValue{version: vm.version, value: value}))
+	return vm.version
+}
+
+// Delete not exist key still make a new version
+func (vm *versionedMap) Delete(key string) (version int64) {
+	vm.Lock()
+	defer vm.Unlock()
+
+	vm.version++
+
+	h := vm.history(key)
+	if len(h) == 0 || h[len(h)-1].deleted {
+		return vm.version
+	}
+
+	vm.len--
+	vm.m.Put(key, append(h, versionedValue{version: vm.version, deleted: true}))
+	return vm.version
+}
+
+func (vm *versionedMap) Get(key string) (value interface{}, exist bool) {
+	vm.RLock()
+	defer vm.RUnlock()
+
+	h := vm.history(key)
+	if len(h) == 0 || h[len(h)-1].deleted {
+		return nil, false
+	}
+
+	return h[len(h)-1].value, true
+}
+
+func (vm *versionedMap) GetAt(key string, version int64) (value interface{}, exist bool, err error) {
+	vm.RLock()
+	defer vm.RUnlock()
+
+	if err = vm.checkVersion(version); err != nil {
+		return
+	}
+
+	value, exist = lookupVersion(vm.history(key), version)
+	return
+}
+
+// IteratorAt iterate on snapshot of rbt, writer can keep going
+func (vm *versionedMap) IteratorAt(version int64) (MapIterator, error) {
+	vm.RLock()
+	defer vm.RUnlock()
+
+	if err := vm.checkVersion(version); err != nil {
+		return nil, err
+	}
+
+	return &versionIterator{
+		it:      vm.m.Snapshot().AscendIterator(),
+		version: version,
+	}, nil
+}
+
+// Compact beforeVersion greater than newest version will be newest version
+// after compact, read at version less than beforeVersion return ErrVersionCompacted
+func (vm *versionedMap) Compact(beforeVersion int64) {
+	vm.Lock()
+	defer vm.Unlock()
+
+	if beforeVersion > vm.version {
+		beforeVersion = vm.version
+	}
+
+	if beforeVersion <= vm.compacted {
+		return
+	}
+
+	vm.compacted = beforeVersion
+
+	// collect first, can not write map when walk it
+	type compaction struct {
+		key string
+		h   []versionedValue
+	}
+	changes := make([]compaction, 0)
+	vm.m.Ascend(func(key string, value interface{}) bool {
+		h := value.([]versionedValue)
+
+		// the last history at or before beforeVersion still need for reading at beforeVersion, unless it is delete
+		i := sort.Search(len(h), func(i int) bool {
+			return h[i].version > beforeVersion
+		}) - 1
+
+		if i < 0 {
+			return true
+		}
+
+		if h[i].deleted {
+			i++
+		} else if i == 0 {
+			return true
+		}
+
+		// copy to new slice, snapshot may still read the old one
+		changes = append(changes, compaction{key: key, h: append([]versionedValue(nil), h[i:]...)})
+		return true
+	})
+
+	for _, c := range changes {
+		if len(c.h) == 0 {
+			vm.m.Delete(c.key)
+		} else {
+			vm.m.Put(c.key, c.h)
+		}
+	}
+}
+
+func (vm *versionedMap) Version() int64 {
+	vm.RLock()
+	defer vm.RUnlock()
+
+	return vm.version
+}
+
+func (vm *versionedMap) Len() int64 {
+	vm.RLock()
+	defer vm.RUnlock()
+
+	return vm.len
+}
+
+// version can read, caller should lock first
+func (vm *versionedMap) checkVersion(version int64) error {
+	if version < 0 || version > vm.version {
+		return ErrVersionNotExist
+	}
+
+	if version < vm.compacted {
+		return ErrVersionCompacted
+	}
+
+	return nil
+}
+
+// iterate keys exist at version, skip keys not exist
+type versionIterator struct {
+	it      MapIterator // sorted iterator of history
+	version int64       // version to read
+
+	// next key pairs
+	ok    bool
+	key   string
+	value interface{}
+}
+
+func (it *versionIterator) HasNext() bool {
+	for !it.ok && it.it.HasNext() {
+		k, h := it.it.Next()
+		it.value, it.ok = lookupVersion(h.([]versionedValue), it.version)
+		it.key = k
+	}
+
+	return it.ok
+}
+
+func (it *versionIterator) Next() (key string, value interface{}) {
+	// panic here
+	if !it.HasNext() {
+		panic("Next() empty")
+	}
+
+	it.ok = false
+	return it.key, it.value
+}
+
+func (it *versionIterator) Err() error {
+	return it.it.Err()
+}
@@ -0,0 +1,135 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestVersionedMap(t *testing.T) {
+	vm := NewVersionedMap()
+	r := rand.New(rand.NewSource(int64(randNum)))
+
+	// key pairs of every version
+	versions := []map[string]interface{}{{}}
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("%d", r.Int63n(200))
+		rw := make(map[string]interface{}, len(versions[len(versions)-1]))
+		for k, v := range versions[len(versions)-1] {
+			rw[k] = v
+		}
+
+		var version int64
+		if r.Intn(3) == 0 {
+			version = vm.Delete(key)
+			delete(rw, key)
+		} else {
+			version = vm.Put(key, i)
+			rw[key] = i
+		}
+
+		if version != int64(len(versions)) {
+			t.Fatalf("write %d get version %d", i, version)
+		}
+
+		versions = append(versions, rw)
+	}
+
+	if vm.Version() != 2000 || vm.Len() != int64(len(versions[2000])) {
+		t.Fatalf("version %d len %d", vm.Version(), vm.Len())
+	}
+
+	check := func(from int64) {
+		for version := from; version < int64(len(versions)); version += 7 {
+			rw := versions[version]
+			for i := 0; i < 200; i++ {
+				key := fmt.Sprintf("%d", i)
+				value, exist, err := vm.GetAt(key, version)
+				if want, ok := rw[key]; err != nil || ok != exist || want != value {
+					t.Fatalf("version %d key %s get %v %v %v want %v", version, key, value, exist, err, want)
+				}
+			}
+
+			keyList := make([]string, 0, len(rw))
+			for k := range rw {
+				keyList = append(keyList, k)
+			}
+			sort.Strings(keyList)
+
+			it, err := vm.IteratorAt(version)
+			if err != nil {
+				t.Fatalf("version %d iterator err %v", version, err)
+			}
+
+			n := 0
+			for it.HasNext() {
+				k, v := it.Next()
+				if n >= len(keyList) || k != keyList[n] || v != rw[k] {
+					t.Fatalf("version %d iterator %d get %s", version, n, k)
+				}
+				n++
+			}
+
+			if n != len(keyList) {
+				t.Fatalf("version %d iterator %d keys want %d", version, n, len(keyList))
+			}
+		}
+	}
+
+	check(0)
+
+	// iterator at version not change by later write
+	it, _ := vm.IteratorAt(1000)
+	vm.Put("new", "new")
+	vm.Delete("1")
+	versions = append(versions, nil, nil)
+	n := 0
+	for it.HasNext() {
+		it.Next()
+		n++
+	}
+	if n != len(versions[1000]) || it.Err() != nil {
+		t.Fatalf("iterator at 1000 get %d keys err %v", n, it.Err())
+	}
+
+	if _, _, err := vm.GetAt("1", vm.Version()+1); err != ErrVersionNotExist {
+		t.Fatalf("future version err %v", err)
+	}
+
+	// compact drop history before 1500
+	vm.Compact(1500)
+	check(1500)
+	if _, _, err := vm.GetAt("1", 1499); err != ErrVersionCompacted {
+		t.Fatalf("compacted version err %v", err)
+	}
+
+	if _, err := vm.IteratorAt(1000); err != ErrVersionCompacted {
+		t.Fatalf("compacted iterator err %v", err)
+	}
+
+	// compact to newest version keep only newest key pairs
+	vm.Compact(vm.Version() + 100)
+	if v, exist := vm.Get("new"); !exist || v != "new" {
+		t.Fatalf("get new after compact %v %v", v, exist)
+	}
+
+	if _, exist := vm.Get("1"); exist {
+		t.Fatalf("get deleted key after compact")
+	}
+
+	it, _ = vm.IteratorAt(vm.Version())
+	n = 0
+	for it.HasNext() {
+		it.Next()
+		n++
+	}
+	if int64(n) != vm.Len() {
+		t.Fatalf("newest iterator get %d keys want %d", n, vm.Len())
+	}
+}
@@ -0,0 +1,58 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+// WalkFunc call by Ascend, Descend ... for every key pairs, return false to stop walking
+// fn is called with map lock held, so it must not call any method of the same map
+type WalkFunc func(key string, value interface{}) bool
+
+// 中序遍历，只遍历 ge <= key < lt 的节点，边界为 nil 表示不限制
+// return false if fn stop walking
+func ascend(node bsTreeNode, c comparator, ge, lt *string, fn WalkFunc) bool {
+	if node == nil {
+		return true
+	}
+
+	k, v := node.values()
+
+	// 该节点和左子树都比下界小，只需要遍历右子树
+	if ge != nil && c(k, *ge) < 0 {
+		return ascend(node.rightOf(), c, ge, lt, fn)
+	}
+
+	// 该节点和右子树都不比上界小，只需要遍历左子树
+	if lt != nil && c(k, *lt) >= 0 {
+		return ascend(node.leftOf(), c, ge, lt, fn)
+	}
+
+	if !ascend(node.leftOf(), c, ge, lt, fn) {
+		return false
+	}
+
+	if !fn(k, v) {
+		return false
+	}
+
+	return ascend(node.rightOf(), c, ge, lt, fn)
+}
+
+// 逆中序遍历
+// return false if fn stop walking
+func descend(node bsTreeNode, fn WalkFunc) bool {
+	if node == nil {
+		return true
+	}
+
+	if !descend(node.rightOf(), fn) {
+		return false
+	}
+
+	if !fn(node.values()) {
+		return false
+	}
+
+	return descend(node.leftOf(), fn)
+}
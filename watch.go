@@ -0,0 +1,179 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// default channel buffer of watcher
+const watchBufferDefault = 64
+
+// EventType type of watch event
+type EventType int
+
+const (
+	EventPut    EventType = iota // key add or update
+	EventDelete                  // key delete
+)
+
+// WatchEvent one change of key
+type WatchEvent struct {
+	Type     EventType   // put or delete
+	Key      string      // key changed
+	Value    interface{} // new value, nil when delete
+	OldValue interface{} // value before change
+	OldExist bool        // key exist before change, always true when delete
+	Dropped  int64       // num of events dropped before this one, only OverflowDrop
+}
+
+// OverflowPolicy what writer do when channel buffer of watcher is full
+type OverflowPolicy int
+
+const (
+	OverflowDrop  OverflowPolicy = iota // drop the event, writer never wait, next event count it in Dropped
+	OverflowBlock                       // writer wait until watcher receive it or ctx done
+)
+
+// WatchOption option of watch
+// OverflowBlock make writer wait with map lock held, watcher must not call the same map before receive the event
+type WatchOption struct {
+	Buffer   int            // channel buffer size, less than 1 will be 64
+	Overflow OverflowPolicy // default OverflowDrop
+}
+
+// one subscriber
+type watcher struct {
+	ctx     context.Context // channel closed after ctx done
+	ch      chan WatchEvent // events out
+	key     string          // key or prefix to watch
+	prefix  bool            // key is a prefix
+	policy  OverflowPolicy  // buffer full policy
+	dropped int64           // num of events dropped since last sent
+}
+
+func (w *watcher) match(key string) bool {
+	if w.prefix {
+		return strings.HasPrefix(key, w.key)
+	}
+
+	return w.key == key
+}
+
+func (w *watcher) send(ev WatchEvent) {
+	if w.policy == OverflowBlock {
+		select {
+		case w.ch <- ev:
+		case <-w.ctx.Done():
+		}
+		return
+	}
+
+	dropped := atomic.LoadInt64(&w.dropped)
+	ev.Dropped = dropped
+	select {
+	case w.ch <- ev:
+		atomic.AddInt64(&w.dropped, -dropped)
+	default:
+		atomic.AddInt64(&w.dropped, 1)
+	}
+}
+
+// all subscribers of a map, writer send event to them
+// sender hold read lock, so channel only closed when no one sending
+type watchHub struct {
+	n            int32                 // num of watcher, writer skip building event when 0
+	watchers     map[*watcher]struct{} // all watcher
+	sync.RWMutex                       // lock for watchers
+}
+
+func newWatchHub() *watchHub {
+	return &watchHub{
+		watchers: make(map[*watcher]struct{}),
+	}
+}
+
+// hub of map created at first watch, writer read it under lock
+func lazyWatchHub(l sync.Locker, hub **watchHub) *watchHub {
+	l.Lock()
+	defer l.Unlock()
+
+	if *hub == nil {
+		*hub = newWatchHub()
+	}
+
+	return *hub
+}
+
+// add a watcher, remove it and close channel after ctx done
+func (h *watchHub) watch(ctx context.Context, key string, prefix bool, opt WatchOption) <-chan WatchEvent {
+	if opt.Buffer < 1 {
+		opt.Buffer = watchBufferDefault
+	}
+
+	w := &watcher{
+		ctx:    ctx,
+		ch:     make(chan WatchEvent, opt.Buffer),
+		key:    key,
+		prefix: prefix,
+		policy: opt.Overflow,
+	}
+
+	h.Lock()
+	h.watchers[w] = struct{}{}
+	atomic.AddInt32(&h.n, 1)
+	h.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		h.Lock()
+		delete(h.watchers, w)
+		atomic.AddInt32(&h.n, -1)
+		h.Unlock()
+
+		close(w.ch)
+	}()
+
+	return w.ch
+}
+
+// hub is nil when no one ever watch
+func (h *watchHub) active() bool {
+	return h != nil && atomic.LoadInt32(&h.n) > 0
+}
+
+func (h *watchHub) notify(ev WatchEvent) {
+	h.RLock()
+	defer h.RUnlock()
+
+	for w := range h.watchers {
+		if w.match(ev.Key) {
+			w.send(ev)
+		}
+	}
+}
+
+// key put, call after map changed
+func (h *watchHub) put(key string, value, old interface{}, oldExist bool) {
+	if !h.active() {
+		return
+	}
+
+	h.notify(WatchEvent{Type: EventPut, Key: key, Value: value, OldValue: old, OldExist: oldExist})
+}
+
+// key delete, call after map changed
+func (h *watchHub) delete(key string, old interface{}) {
+	if !h.active() {
+		return
+	}
+
+	h.notify(WatchEvent{Type: EventDelete, Key: key, OldValue: old, OldExist: true})
+}
@@ -0,0 +1,999 @@
+/*
+	All right reserved：https://github.com/hunterhug/gomap at 2020
+	Attribution-NonCommercial-NoDerivatives 4.0 International
+	You can use it for education only but can't make profits for any companies and individuals!
+*/
+package gomap
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	wbtAlpha    = 0.25             // default alpha of weight balanced tree
+	wbtAlphaMin = 2.0 / 11         // alpha must greater than it, or one rotation can not fix balance
+	wbtAlphaMax = 1 - math.Sqrt2/2 // alpha must not greater than it, or tree can not be balanced
+)
+
+// Weight Balanced Tree, BB[alpha] of Nievergelt and Reingold, node keep no color, balance factor or height, only size of sub tree
+// weight of node is size+1, weight of every child is at least alpha*weight of node, fix by single or double rotation
+type wbtTree struct {
+	modCount     int64      // num of add or delete key, iterator use it to fail fast
+	c            comparator // tree key compare
+	root         *wbtNode   // tree root
+	len          int64      // tree key pairs num
+	alpha        float64    // weight of child >= alpha*weight of node, in (2/11, 1-sqrt(2)/2]
+	single       float64    // heavy child of which inner grandchild weight <= single*weight of it only need single rotation
+	watch        *watchHub  // subscribers of change, nil until first watch
+	stats        mapStats   // live counters
+	sync.RWMutex            // lock for concurrent safe, read lock for lookup
+}
+
+type wbtNode struct {
+	k     string      // key
+	v     interface{} // value
+	left  *wbtNode
+	right *wbtNode
+	size  int64 // key pairs num of the sub tree
+}
+
+// NewWBTMap new a weight balanced tree map, alpha is 0.25
+func NewWBTMap() Map {
+	return NewWBTMapWithAlpha(wbtAlpha)
+}
+
+// NewWBTMapWithAlpha new a weight balanced tree map, alpha in (2/11, 1-sqrt(2)/2], otherwise use 0.25
+// big alpha keep tree low but rotate more often
+func NewWBTMapWithAlpha(alpha float64) Map {
+	if !(alpha > wbtAlphaMin && alpha <= wbtAlphaMax) {
+		alpha = wbtAlpha
+	}
+
+	t := new(wbtTree)
+	t.c = comparatorDefault
+	t.alpha = alpha
+	t.single = (1 - 2*alpha) / (1 - alpha)
+	return t
+}
+
+// cal height, walk all nodes of sub tree
+func (node *wbtNode) height() int64 {
+	if node == nil {
+		return 0
+	}
+
+	lh := node.left.height()
+	rh := node.right.height()
+	if lh > rh {
+		return lh + 1
+	}
+
+	return rh + 1
+}
+
+// cal sub tree size
+func (node *wbtNode) treeSize() int64 {
+	if node == nil {
+		return 0
+	}
+
+	return node.size
+}
+
+// weight of sub tree, num of nil links, empty tree is 1
+func (node *wbtNode) weight() float64 {
+	return float64(node.treeSize() + 1)
+}
+
+// Height height of tree, walk all nodes, O(N)
+func (tree *wbtTree) Height() int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.root.height()
+}
+
+// Stats live counters
+func (tree *wbtTree) Stats() Stats {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := tree.stats.load()
+	s.Len = tree.len
+	s.Height = tree.root.height()
+	s.HeightBound = wbtHeightBound(s.Len, tree.alpha)
+	return s
+}
+
+// right child rise to be root of sub tree, return it
+func (tree *wbtTree) rotateLeft(h *wbtNode) *wbtNode {
+	atomic.AddInt64(&tree.stats.leftRotations, 1)
+
+	x := h.right
+	h.right = x.left
+	x.left = h
+
+	// x take place of h, so size of h change
+	x.size = h.size
+	h.size = h.left.treeSize() + h.right.treeSize() + 1
+	return x
+}
+
+// left child rise to be root of sub tree, return it
+func (tree *wbtTree) rotateRight(h *wbtNode) *wbtNode {
+	atomic.AddInt64(&tree.stats.rightRotations, 1)
+
+	x := h.left
+	h.left = x.right
+	x.right = h
+
+	x.size = h.size
+	h.size = h.left.treeSize() + h.right.treeSize() + 1
+	return x
+}
+
+// weight of one child of h change by one, fix h and return new root of sub tree
+// inner grandchild of heavy child too heavy need double rotation, otherwise single
+// weight of small sub tree is not continuous, nodes moved down may be out of balance, fix them again
+func (tree *wbtTree) balance(h *wbtNode) *wbtNode {
+	h.size = h.left.treeSize() + h.right.treeSize() + 1
+	w := h.weight()
+
+	if h.left.weight() < tree.alpha*w {
+		atomic.AddInt64(&tree.stats.rebalances, 1)
+		if h.right.left.weight() > tree.single*h.right.weight() {
+			h.right = tree.rotateRight(h.right)
+			h.right.right = tree.balance(h.right.right)
+		}
+
+		h = tree.rotateLeft(h)
+		h.left = tree.balance(h.left)
+		return tree.balance(h)
+	}
+
+	if h.right.weight() < tree.alpha*w {
+		atomic.AddInt64(&tree.stats.rebalances, 1)
+		if h.left.right.weight() > tree.single*h.left.weight() {
+			h.left = tree.rotateLeft(h.left)
+			h.left.left = tree.balance(h.left.left)
+		}
+
+		h = tree.rotateRight(h)
+		h.right = tree.balance(h.right)
+		return tree.balance(h)
+	}
+
+	return h
+}
+
+func (tree *wbtTree) Put(key string, value interface{}) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.put(key, value)
+}
+
+// put key pairs without lock, caller should lock first
+func (tree *wbtTree) put(key string, value interface{}) {
+	var n int64
+	tree.root = tree.insert(tree.root, key, value, &n)
+	tree.stats.compare(key, n)
+}
+
+// insert to sub tree of h, return new root of sub tree, n count compares
+func (tree *wbtTree) insert(h *wbtNode, key string, value interface{}, n *int64) *wbtNode {
+	if h == nil {
+		tree.len++
+		atomic.AddInt64(&tree.modCount, 1)
+		atomic.AddInt64(&tree.stats.puts, 1)
+		tree.watch.put(key, value, nil, false)
+		return &wbtNode{
+			k:    key,
+			v:    value,
+			size: 1,
+		}
+	}
+
+	*n++
+	cmp := tree.c(key, h.k)
+	if cmp < 0 {
+		h.left = tree.insert(h.left, key, value, n)
+	} else if cmp > 0 {
+		h.right = tree.insert(h.right, key, value, n)
+	} else {
+		// update value, weight not change, no rotation on the way back
+		tree.update(h, value)
+		return h
+	}
+
+	return tree.balance(h)
+}
+
+// update value of node, without lock
+func (tree *wbtTree) update(node *wbtNode, value interface{}) {
+	old := node.v
+	node.v = value
+	atomic.AddInt64(&tree.stats.puts, 1)
+	tree.watch.put(node.k, value, old, true)
+}
+
+func (tree *wbtTree) Delete(key string) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	tree.deleteKey(key)
+}
+
+// delete key without lock, caller should lock first
+// find first, tree not change when key not exist, so iterator not fail
+func (tree *wbtTree) deleteKey(key string) (value interface{}, exist bool) {
+	node := tree.find(key)
+	if node == nil {
+		return
+	}
+
+	value = node.v
+	tree.root = tree.delete(tree.root, key)
+	tree.deleted(key, value)
+	return value, true
+}
+
+// count a key deleted
+func (tree *wbtTree) deleted(key string, value interface{}) {
+	tree.len--
+	atomic.AddInt64(&tree.modCount, 1)
+	atomic.AddInt64(&tree.stats.deletes, 1)
+	tree.watch.delete(key, value)
+}
+
+// delete key from sub tree of h, key must exist, return new root of sub tree
+// node has two children take key pairs of successor, then successor is deleted instead
+func (tree *wbtTree) delete(h *wbtNode, key string) *wbtNode {
+	cmp := tree.c(key, h.k)
+	if cmp < 0 {
+		h.left = tree.delete(h.left, key)
+	} else if cmp > 0 {
+		h.right = tree.delete(h.right, key)
+	} else {
+		if h.left == nil {
+			return h.right
+		}
+
+		if h.right == nil {
+			return h.left
+		}
+
+		var m *wbtNode
+		h.right, m = tree.deleteMin(h.right)
+		h.k, h.v = m.k, m.v
+	}
+
+	return tree.balance(h)
+}
+
+// delete min node of sub tree of h, return new root of sub tree and the min node
+func (tree *wbtTree) deleteMin(h *wbtNode) (root, node *wbtNode) {
+	if h.left == nil {
+		return h.right, h
+	}
+
+	h.left, node = tree.deleteMin(h.left)
+	return tree.balance(h), node
+}
+
+// delete max node of sub tree of h, return new root of sub tree and the max node
+func (tree *wbtTree) deleteMax(h *wbtNode) (root, node *wbtNode) {
+	if h.right == nil {
+		return h.left, h
+	}
+
+	h.right, node = tree.deleteMax(h.right)
+	return tree.balance(h), node
+}
+
+// find key in tree
+func (tree *wbtTree) find(key string) *wbtNode {
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 {
+			break
+		} else if cmp < 0 {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return node
+}
+
+// MinKey find min key pairs
+func (tree *wbtTree) MinKey() (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	node := tree.root.minNode()
+	return node.k, node.v, true
+}
+
+func (node *wbtNode) minNode() *wbtNode {
+	for node.left != nil {
+		node = node.left
+	}
+
+	return node
+}
+
+// MaxKey find max key pairs
+func (tree *wbtTree) MaxKey() (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	node := tree.root.maxNode()
+	return node.k, node.v, true
+}
+
+func (node *wbtNode) maxNode() *wbtNode {
+	for node.right != nil {
+		node = node.right
+	}
+
+	return node
+}
+
+// Floor find the greatest key pairs less than or equal to key
+func (tree *wbtTree) Floor(key string) (floorKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.floor(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Ceiling find the least key pairs greater than or equal to key
+func (tree *wbtTree) Ceiling(key string) (ceilingKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, true)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Lower find the greatest key pairs strictly less than key
+func (tree *wbtTree) Lower(key string) (lowerKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.floor(key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// Higher find the least key pairs strictly greater than key
+func (tree *wbtTree) Higher(key string) (higherKey string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	node := tree.ceiling(key, false)
+	if node == nil {
+		return
+	}
+
+	return node.k, node.v, true
+}
+
+// the greatest node less than key, less than or equal to key when inclusive
+func (tree *wbtTree) floor(key string, inclusive bool) *wbtNode {
+	var candidate *wbtNode
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp > 0 {
+			candidate = node
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// the least node greater than key, greater than or equal to key when inclusive
+func (tree *wbtTree) ceiling(key string, inclusive bool) *wbtNode {
+	var candidate *wbtNode
+	var n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+
+		if cmp < 0 {
+			candidate = node
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return candidate
+}
+
+// Rank num of keys strictly less than key
+func (tree *wbtTree) Rank(key string) int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	var rank, n int64
+	node := tree.root
+	for node != nil {
+		n++
+		cmp := tree.c(key, node.k)
+		if cmp > 0 {
+			rank += node.left.treeSize() + 1
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+
+	tree.stats.compare(key, n)
+	return rank
+}
+
+// Select find the i-th smallest key pairs, i start from 0
+func (tree *wbtTree) Select(i int64) (key string, value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if i < 0 || i >= tree.root.treeSize() {
+		return
+	}
+
+	node := tree.root
+	for node != nil {
+		leftSize := node.left.treeSize()
+		if i < leftSize {
+			node = node.left
+		} else if i == leftSize {
+			return node.k, node.v, true
+		} else {
+			i = i - leftSize - 1
+			node = node.right
+		}
+	}
+
+	return
+}
+
+// Get find value of key
+func (tree *wbtTree) Get(key string) (value interface{}, exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
+
+	return tree.get(key)
+}
+
+// get value of key without lock, caller should lock first
+func (tree *wbtTree) get(key string) (value interface{}, exist bool) {
+	if node := tree.find(key); node != nil {
+		return node.v, true
+	}
+
+	return
+}
+
+// Contains key exist or not
+func (tree *wbtTree) Contains(key string) (exist bool) {
+	tree.RLock()
+	defer tree.RUnlock()
+	tree.stats.get(key)
+
+	return tree.find(key) != nil
+}
+
+func (tree *wbtTree) Len() int64 {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.len
+}
+
+func (tree *wbtTree) GetInt(key string) (value int, exist bool, err error) {
+	return getInt(tree.Get, key)
+}
+
+func (tree *wbtTree) GetInt64(key string) (value int64, exist bool, err error) {
+	return getInt64(tree.Get, key)
+}
+
+func (tree *wbtTree) GetString(key string) (value string, exist bool, err error) {
+	return getString(tree.Get, key)
+}
+
+func (tree *wbtTree) GetFloat64(key string) (value float64, exist bool, err error) {
+	return getFloat64(tree.Get, key)
+}
+
+func (tree *wbtTree) GetBytes(key string) (value []byte, exist bool, err error) {
+	return getBytes(tree.Get, key)
+}
+
+func (tree *wbtTree) KeySortedList() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, nil, func(key string, value interface{}) bool {
+			keyList = append(keyList, key)
+			return true
+		})
+	}
+
+	return keyList
+}
+
+func (tree *wbtTree) KeySortedListDesc() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	if tree.root != nil {
+		descend(tree.root, func(key string, value interface{}) bool {
+			keyList = append(keyList, key)
+			return true
+		})
+	}
+
+	return keyList
+}
+
+// Check binary search tree by key and size is right, weight of every child is at least alpha*weight of node
+func (tree *wbtTree) Check() bool {
+	if tree == nil || tree.root == nil {
+		return true
+	}
+
+	if tree.root.size != tree.len {
+		fmt.Printf("root size %d != len %d\n", tree.root.size, tree.len)
+		return false
+	}
+
+	if !tree.root.isBST(tree.c, nil, nil) {
+		fmt.Println("is not BST")
+		return false
+	}
+
+	if !tree.root.isWeightBalanced(tree.alpha) {
+		fmt.Println("is not weight balanced")
+		return false
+	}
+
+	return true
+}
+
+// weight of both children of every node in sub tree >= alpha*weight of node
+func (node *wbtNode) isWeightBalanced(alpha float64) bool {
+	if node == nil {
+		return true
+	}
+
+	w := node.weight()
+	if node.left.weight() < alpha*w || node.right.weight() < alpha*w {
+		fmt.Printf("key %s weight %.0f, left %.0f, right %.0f\n", node.k, w, node.left.weight(), node.right.weight())
+		return false
+	}
+
+	return node.left.isWeightBalanced(alpha) && node.right.isWeightBalanced(alpha)
+}
+
+// check sub tree, all keys in (lo, hi), nil means no bound
+func (node *wbtNode) isBST(compare comparator, lo, hi *string) bool {
+	if node == nil {
+		return true
+	}
+
+	if (lo != nil && compare(*lo, node.k) >= 0) || (hi != nil && compare(node.k, *hi) >= 0) {
+		fmt.Printf("key %s is not sorted\n", node.k)
+		return false
+	}
+
+	if node.size != node.left.treeSize()+node.right.treeSize()+1 {
+		fmt.Printf("size %d != %d+%d+1\n", node.size, node.left.treeSize(), node.right.treeSize())
+		return false
+	}
+
+	return node.left.isBST(compare, lo, &node.k) && node.right.isBST(compare, &node.k, hi)
+}
+
+func (node *wbtNode) leftOf() bsTreeNode {
+	if node.left == nil {
+		return nil
+	}
+
+	return node.left
+}
+
+func (node *wbtNode) rightOf() bsTreeNode {
+	if node.right == nil {
+		return nil
+	}
+
+	return node.right
+}
+
+// not check node nil, may be panic, user should deal by oneself
+func (node *wbtNode) values() (key string, value interface{}) {
+	return node.k, node.v
+}
+
+func (tree *wbtTree) KeyList() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	keyList := make([]string, 0, tree.len)
+	iterator := tree.iterator()
+	for iterator.HasNext() {
+		k, _ := iterator.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList
+}
+
+func (tree *wbtTree) Iterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.iterator()
+}
+
+// layer order iterator, without lock
+func (tree *wbtTree) iterator() MapIterator {
+	q := new(linkQueue)
+	q.bind(&tree.modCount)
+	if tree.root != nil {
+		q.add(tree.root)
+	}
+	return q
+}
+
+// SafeIterator sorted iterator safe under concurrent write, snapshot mode is consistent, weak mode find next key every step
+func (tree *wbtTree) SafeIterator(mode IteratorMode) MapIterator {
+	return newSafeIterator(tree, mode)
+}
+
+// AscendIterator iterator sorted by key, from min to max
+func (tree *wbtTree) AscendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := new(linkStack)
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// DescendIterator iterator sorted by key, from max to min
+func (tree *wbtTree) DescendIterator() MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	s := &linkStack{desc: true}
+	s.bind(&tree.modCount)
+	if tree.root != nil {
+		s.pushPath(tree.root)
+	}
+	return s
+}
+
+// Range iterator key between from and to, sorted by key
+func (tree *wbtTree) Range(from, to string, opt RangeOption) MapIterator {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return tree.rangeIterator(from, to, opt)
+}
+
+// range iterator without lock
+func (tree *wbtTree) rangeIterator(from, to string, opt RangeOption) MapIterator {
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newRangeIterator(root, tree.c, from, to, opt)
+	it.bind(&tree.modCount)
+	return it
+}
+
+// Cursor bidirectional cursor, before min key at first
+func (tree *wbtTree) Cursor() Cursor {
+	return newCursor(tree)
+}
+
+// PrefixIterator iterator key with prefix, sorted by key
+func (tree *wbtTree) PrefixIterator(prefix string) (MapIterator, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return it, nil
+}
+
+// KeysWithPrefix key with prefix out to list sorted
+func (tree *wbtTree) KeysWithPrefix(prefix string) ([]string, error) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	it, err := tree.prefixIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keyList := make([]string, 0)
+	for it.HasNext() {
+		k, _ := it.Next()
+		keyList = append(keyList, k)
+	}
+
+	return keyList, nil
+}
+
+func (tree *wbtTree) prefixIterator(prefix string) (*rangeIterator, error) {
+	if !isDefaultComparator(tree.c) {
+		return nil, ErrPrefixComparator
+	}
+
+	var root bsTreeNode
+	if tree.root != nil {
+		root = tree.root
+	}
+
+	it := newPrefixIterator(root, tree.c, prefix)
+	it.bind(&tree.modCount)
+	return it, nil
+}
+
+// DeleteRange delete keys which from <= key <= to, return num of deleted keys
+func (tree *wbtTree) DeleteRange(from, to string) int64 {
+	return deleteRange(tree, from, to)
+}
+
+// PopMin find min key pairs and delete it
+func (tree *wbtTree) PopMin() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	var node *wbtNode
+	tree.root, node = tree.deleteMin(tree.root)
+	tree.deleted(node.k, node.v)
+	return node.k, node.v, true
+}
+
+// PopMax find max key pairs and delete it
+func (tree *wbtTree) PopMax() (key string, value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	if tree.root == nil {
+		return
+	}
+
+	var node *wbtNode
+	tree.root, node = tree.deleteMax(tree.root)
+	tree.deleted(node.k, node.v)
+	return node.k, node.v, true
+}
+
+// Ascend walk all key pairs from min to max, stop when fn return false
+func (tree *wbtTree) Ascend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, nil, fn)
+	}
+}
+
+// Descend walk all key pairs from max to min, stop when fn return false
+func (tree *wbtTree) Descend(fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		descend(tree.root, fn)
+	}
+}
+
+// AscendGreaterOrEqual walk key pairs which pivot <= key, stop when fn return false
+func (tree *wbtTree) AscendGreaterOrEqual(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &pivot, nil, fn)
+	}
+}
+
+// AscendLessThan walk key pairs which key < pivot, stop when fn return false
+func (tree *wbtTree) AscendLessThan(pivot string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, nil, &pivot, fn)
+	}
+}
+
+// AscendRange walk key pairs which greaterOrEqual <= key < lessThan, stop when fn return false
+func (tree *wbtTree) AscendRange(greaterOrEqual, lessThan string, fn WalkFunc) {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	if tree.root != nil {
+		ascend(tree.root, tree.c, &greaterOrEqual, &lessThan, fn)
+	}
+}
+
+func (tree *wbtTree) SetComparator(c comparator) Map {
+	tree.Lock()
+	defer tree.Unlock()
+	if tree.len == 0 {
+		tree.c = c
+	}
+
+	return tree
+}
+
+// Snapshot read only view of tree now
+// node has no gen to share with snapshot, copy all nodes under read lock, writers wait, cost O(N)
+func (tree *wbtTree) Snapshot() ReadOnlyMap {
+	tree.RLock()
+	defer tree.RUnlock()
+
+	return readOnlyMap{&wbtTree{
+		c:      tree.c,
+		root:   tree.root.clone(),
+		len:    tree.len,
+		alpha:  tree.alpha,
+		single: tree.single,
+	}}
+}
+
+// copy whole sub tree
+func (node *wbtNode) clone() *wbtNode {
+	if node == nil {
+		return nil
+	}
+
+	n := new(wbtNode)
+	*n = *node
+	n.left = node.left.clone()
+	n.right = node.right.clone()
+	return n
+}
+
+// Begin transaction, all writes apply on commit under one lock
+func (tree *wbtTree) Begin() Txn {
+	return beginTxn(tree)
+}
+
+// Compute put value return by fn, delete key when fn return keep false
+func (tree *wbtTree) Compute(key string, fn ComputeFunc) (value interface{}, exist bool) {
+	return tree.computeOp(key, fn.op())
+}
+
+// compute with lock, nothing write when fn leave the key unchanged
+func (tree *wbtTree) computeOp(key string, fn computeOpFunc) (value interface{}, exist bool) {
+	tree.Lock()
+	defer tree.Unlock()
+
+	// rotation happen on the way back, so write go down again after find
+	node := tree.find(key)
+	if node == nil {
+		value, op := fn(nil, false)
+		if op != computePut {
+			return nil, false
+		}
+
+		tree.put(key, value)
+		return value, true
+	}
+
+	value, op := fn(node.v, true)
+	switch op {
+	case computePut:
+		tree.update(node, value)
+		return value, true
+	case computeDelete:
+		old := node.v
+		tree.root = tree.delete(tree.root, key)
+		tree.deleted(key, old)
+		return nil, false
+	}
+
+	return node.v, true
+}
+
+// PutIfAbsent put if key not exist, otherwise return the exist value
+func (tree *wbtTree) PutIfAbsent(key string, value interface{}) (actual interface{}, loaded bool) {
+	return putIfAbsent(tree.computeOp, key, value)
+}
+
+// Replace put only if key exist, return the old value
+func (tree *wbtTree) Replace(key string, value interface{}) (old interface{}, replaced bool) {
+	return replace(tree.computeOp, key, value)
+}
+
+// CompareAndSwap put new only if value of key == old
+func (tree *wbtTree) CompareAndSwap(key string, old, new interface{}) (swapped bool) {
+	return compareAndSwap(tree.computeOp, key, old, new)
+}
+
+// CompareAndDelete delete only if value of key == old
+func (tree *wbtTree) CompareAndDelete(key string, old interface{}) (deleted bool) {
+	return compareAndDelete(tree.computeOp, key, old)
+}
+
+// Watch watch put and delete of key, channel closed after ctx done
+func (tree *wbtTree) Watch(ctx context.Context, key string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, key, false, opt)
+}
+
+// WatchPrefix watch put and delete of keys with prefix
+func (tree *wbtTree) WatchPrefix(ctx context.Context, prefix string, opt WatchOption) <-chan WatchEvent {
+	return lazyWatchHub(tree, &tree.watch).watch(ctx, prefix, true, opt)
+}